@@ -21,3 +21,5 @@ require (
 	golang.org/x/text v0.9.0 // indirect
 	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
 )
+
+replace github.com/vitthalaa/go-grpc-chat => ../../
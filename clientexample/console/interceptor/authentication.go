@@ -2,7 +2,6 @@ package interceptor
 
 import (
 	"context"
-	"fmt"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -14,46 +13,41 @@ var (
 	authorizationKey = "authorization"
 )
 
+// AuthInterceptor sends the session token of the user as bearer authorization
 type AuthInterceptor struct {
-	userName string
+	token string
 }
 
-func NewAuthClientInterceptor(username string) *AuthInterceptor {
-	return &AuthInterceptor{
-		userName: username,
-	}
+func NewAuthClientInterceptor() *AuthInterceptor {
+	return &AuthInterceptor{}
+}
+
+// SetToken sets the session token sent with following calls, it must be set before calls are made concurrently
+func (i *AuthInterceptor) SetToken(token string) {
+	i.token = token
 }
 
 // AuthUnaryClientInterceptor adds authorization to outgoing context
-func (i AuthInterceptor) AuthUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-	md, ok := metadata.FromOutgoingContext(ctx)
-	if !ok {
-		md = metadata.New(map[string]string{})
+func (i *AuthInterceptor) AuthUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	// the token is obtained by registering and logging in
+	if method == pb.ChatService_Register_FullMethodName || method == pb.ChatService_Login_FullMethodName {
+		return invoker(ctx, method, req, reply, cc, opts...)
 	}
 
-	fmt.Println("setting authorization", i.userName)
-
-	md.Set(authorizationKey, i.userName)
-	ctx = metadata.NewOutgoingContext(ctx, md)
-
-	return invoker(ctx, method, req, reply, cc, opts...)
+	return invoker(i.withAuthorization(ctx), method, req, reply, cc, opts...)
 }
 
-func (i AuthInterceptor) AuthStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-	// connect method don't need authentication
-	if method == pb.ChatService_Connect_FullMethodName {
-		return streamer(ctx, desc, cc, method, opts...)
-	}
+func (i *AuthInterceptor) AuthStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(i.withAuthorization(ctx), desc, cc, method, opts...)
+}
 
+func (i *AuthInterceptor) withAuthorization(ctx context.Context) context.Context {
 	md, ok := metadata.FromOutgoingContext(ctx)
 	if !ok {
 		md = metadata.New(map[string]string{})
 	}
 
-	fmt.Println("setting authorization", i.userName)
-
-	md.Set(authorizationKey, i.userName)
-	ctx = metadata.NewOutgoingContext(ctx, md)
+	md.Set(authorizationKey, "Bearer "+i.token)
 
-	return streamer(ctx, desc, cc, method, opts...)
+	return metadata.NewOutgoingContext(ctx, md)
 }
@@ -2,52 +2,185 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net"
+	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/vitthalaa/go-grpc-chat/clientexample/console/interceptor"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/vitthalaa/go-grpc-chat/clientexample/console/oidc"
 	"github.com/vitthalaa/go-grpc-chat/clientexample/console/prompt"
+	"github.com/vitthalaa/go-grpc-chat/clientexample/console/tlsconfig"
 	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
 )
 
+var (
+	addr          = flag.String("addr", "localhost:5400", "address of the chat server")
+	useTLS        = flag.Bool("tls", false, "connect with TLS, implied by the other tls flags")
+	tlsCA         = flag.String("tls-ca", "", "CA file verifying the server certificate, the system roots are used when empty")
+	tlsCert       = flag.String("tls-cert", "", "client certificate file for mutual TLS")
+	tlsKey        = flag.String("tls-key", "", "private key file of the client certificate")
+	tlsServerName = flag.String("tls-server-name", "", "name verified in the server certificate, the host of -addr when empty")
+
+	oidcIssuer      = flag.String("oidc-issuer", "", "OpenID Connect issuer URL, logs in with the device authorization flow instead of a password when set")
+	oidcClientID    = flag.String("oidc-client-id", "", "client id registered at the OpenID Connect issuer")
+	oidcScopes      = flag.String("oidc-scopes", "openid profile", "space separated scopes requested at the OpenID Connect issuer")
+	oidcUserClaim   = flag.String("oidc-username-claim", "preferred_username", "claim of the ID token holding the user name")
+	oidcAccessToken = flag.Bool("oidc-access-token", false, "send the access token to the server instead of the ID token")
+)
+
 func main() {
-	userName := ""
-	err := survey.AskOne(&survey.Input{
-		Message: "Username:",
-	}, &userName)
+	flag.Parse()
 
+	creds, err := transportCredentials()
 	if err != nil {
-		log.Fatalf("failed to read username: %v", err)
+		log.Fatalf("failed to set up TLS: %v", err)
 	}
 
-	fmt.Println("Username:", userName)
-
 	ctx := context.Background()
 
-	authInc := interceptor.NewAuthClientInterceptor(userName)
+	authInc := interceptor.NewAuthClientInterceptor()
 
 	var opts []grpc.DialOption
 	opts = append(opts,
 		grpc.WithBlock(),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 		grpc.WithUnaryInterceptor(authInc.AuthUnaryClientInterceptor),
 		grpc.WithStreamInterceptor(authInc.AuthStreamClientInterceptor),
 	)
 
-	conn, err := grpc.Dial(":5400", opts...)
+	conn, err := grpc.Dial(*addr, opts...)
 	if err != nil {
 		log.Fatalf("failed to dial: %v", err)
 	}
 
 	client := pb.NewChatServiceClient(conn)
 
+	userName := ""
+	if *oidcIssuer != "" {
+		userName, err = oidcLogin(ctx, authInc)
+	} else {
+		userName, err = passwordLogin(ctx, client, authInc)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Username:", userName)
+
 	prompter := prompt.NewPrompter(client, userName)
 	err = prompter.Run(ctx)
 	if err != nil {
 		log.Fatalf("failed to run prompt: %v", err)
 	}
 }
+
+// transportCredentials returns the credentials of the tls flags, plaintext when none is set
+func transportCredentials() (credentials.TransportCredentials, error) {
+	if !*useTLS && *tlsCA == "" && *tlsCert == "" && *tlsKey == "" && *tlsServerName == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	// an address without host like :5400 gives no name to verify the server certificate against
+	serverName := *tlsServerName
+	if host, _, err := net.SplitHostPort(*addr); serverName == "" && err == nil && host == "" {
+		serverName = "localhost"
+	}
+
+	config, err := tlsconfig.New(tlsconfig.Config{
+		CAFile:     *tlsCA,
+		CertFile:   *tlsCert,
+		KeyFile:    *tlsKey,
+		ServerName: serverName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// passwordLogin asks for the credentials of the user, registers a new account when asked to
+// and logs in with the password. It returns the user name.
+func passwordLogin(ctx context.Context, client pb.ChatServiceClient, authInc *interceptor.AuthInterceptor) (string, error) {
+	register := false
+	err := survey.AskOne(&survey.Confirm{
+		Message: "Register a new account?",
+		Default: false,
+	}, &register)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	userName := ""
+	err = survey.AskOne(&survey.Input{
+		Message: "Username:",
+	}, &userName)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read username: %w", err)
+	}
+
+	password := ""
+	err = survey.AskOne(&survey.Password{
+		Message: "Password:",
+	}, &password)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	if register {
+		_, err = client.Register(ctx, &pb.RegisterRequest{
+			Username: userName,
+			Password: password,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to register: %w", err)
+		}
+	}
+
+	res, err := client.Login(ctx, &pb.LoginRequest{
+		Username: userName,
+		Password: password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to login: %w", err)
+	}
+
+	authInc.SetToken(res.GetToken())
+
+	return userName, nil
+}
+
+// oidcLogin logs in at the OpenID Connect issuer with the device authorization flow,
+// the user approves the login in a browser. It returns the user name claim.
+func oidcLogin(ctx context.Context, authInc *interceptor.AuthInterceptor) (string, error) {
+	login, err := oidc.DeviceLogin(ctx, oidc.Config{
+		Issuer:         *oidcIssuer,
+		ClientID:       *oidcClientID,
+		Scopes:         strings.Fields(*oidcScopes),
+		UsernameClaim:  *oidcUserClaim,
+		UseAccessToken: *oidcAccessToken,
+	}, func(code oidc.DeviceCode) {
+		fmt.Printf("To log in, open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+		if code.VerificationURIComplete != "" {
+			fmt.Printf("or open %s\n", code.VerificationURIComplete)
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to login: %w", err)
+	}
+
+	authInc.SetToken(login.Token)
+
+	return login.Username, nil
+}
@@ -0,0 +1,268 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// deviceCodeGrant is the grant type polling the token endpoint of the device authorization flow
+const deviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// defaultInterval polls the token endpoint when the issuer names no interval
+	defaultInterval = 5 * time.Second
+	// intervalUnit is the unit of the interval named by the issuer
+	intervalUnit = time.Second
+	// slowDownIncrease is added to the interval on every slow_down response
+	slowDownIncrease = 5 * time.Second
+)
+
+// Config configures the device authorization login at an OpenID Connect issuer
+type Config struct {
+	// Issuer is the issuer URL, its discovery document names the endpoints
+	Issuer string
+	// ClientID is the public client registered at the issuer
+	ClientID string
+	// Scopes are requested with the device code, openid is always requested
+	Scopes []string
+	// UsernameClaim is the claim of the ID token holding the chat user name, sub when empty
+	UsernameClaim string
+	// UseAccessToken sends the access token to the chat server instead of the ID token
+	UseAccessToken bool
+	// HTTPClient talks to the issuer, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// DeviceCode is what the user needs to approve the login on another device
+type DeviceCode struct {
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+}
+
+// Login is the result of a successful login
+type Login struct {
+	// Token is sent to the chat server as bearer authorization
+	Token string
+	// Username is the user name claim of the login
+	Username string
+}
+
+// DeviceLogin runs the device authorization flow. show is called once with the code the user
+// approves at the issuer, the token endpoint is then polled until the login is approved, denied or expired.
+func DeviceLogin(ctx context.Context, c Config, show func(DeviceCode)) (Login, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var discovery struct {
+		DeviceEndpoint string `json:"device_authorization_endpoint"`
+		TokenEndpoint  string `json:"token_endpoint"`
+	}
+
+	err := getJSON(ctx, client, strings.TrimSuffix(c.Issuer, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return Login{}, fmt.Errorf("discovery: %w", err)
+	}
+
+	if discovery.DeviceEndpoint == "" {
+		return Login{}, errors.New("issuer does not support the device authorization flow")
+	}
+
+	scopes := append([]string{"openid"}, c.Scopes...)
+
+	var code struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+
+	_, err = postForm(ctx, client, discovery.DeviceEndpoint, url.Values{
+		"client_id": {c.ClientID},
+		"scope":     {strings.Join(dedupe(scopes), " ")},
+	}, &code)
+	if err != nil {
+		return Login{}, fmt.Errorf("device authorization: %w", err)
+	}
+
+	show(DeviceCode{
+		UserCode:                code.UserCode,
+		VerificationURI:         code.VerificationURI,
+		VerificationURIComplete: code.VerificationURIComplete,
+	})
+
+	interval := time.Duration(code.Interval) * intervalUnit
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	if code.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return Login{}, errors.New("device code expired")
+		case <-time.After(interval):
+		}
+
+		var token struct {
+			IDToken     string `json:"id_token"`
+			AccessToken string `json:"access_token"`
+		}
+
+		oauthErr, err := postForm(ctx, client, discovery.TokenEndpoint, url.Values{
+			"grant_type":  {deviceCodeGrant},
+			"device_code": {code.DeviceCode},
+			"client_id":   {c.ClientID},
+		}, &token)
+
+		switch oauthErr {
+		case "":
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += slowDownIncrease
+			continue
+		case "access_denied":
+			return Login{}, errors.New("login was denied")
+		case "expired_token":
+			return Login{}, errors.New("device code expired")
+		}
+
+		if err != nil {
+			return Login{}, fmt.Errorf("token: %w", err)
+		}
+
+		return c.login(token.IDToken, token.AccessToken)
+	}
+}
+
+// login picks the token sent to the server and reads the user name from the ID token
+func (c Config) login(idToken, accessToken string) (Login, error) {
+	claim := c.UsernameClaim
+	if claim == "" {
+		claim = "sub"
+	}
+
+	res := Login{Token: idToken}
+	if c.UseAccessToken {
+		res.Token = accessToken
+	}
+
+	if res.Token == "" {
+		return Login{}, errors.New("issuer returned no token")
+	}
+
+	// the server verifies the token, the claims are only read to show who is logged in
+	claims, err := unverifiedClaims(idToken)
+	if err != nil {
+		return Login{}, fmt.Errorf("id token: %w", err)
+	}
+
+	res.Username, _ = claims[claim].(string)
+	if res.Username == "" {
+		return Login{}, fmt.Errorf("id token has no %s claim", claim)
+	}
+
+	return res, nil
+}
+
+func unverifiedClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	err = json.Unmarshal(payload, &claims)
+
+	return claims, err
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// postForm posts form to u and decodes the response into v, an OAuth error response
+// is returned as its error code
+func postForm(ctx context.Context, client *http.Client, u string, form url.Values, v interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+
+		_ = json.NewDecoder(res.Body).Decode(&oauthErr)
+		if oauthErr.Error == "" {
+			return "", fmt.Errorf("POST %s: %s", u, res.Status)
+		}
+
+		return oauthErr.Error, fmt.Errorf("%s: %s", oauthErr.Error, oauthErr.Description)
+	}
+
+	return "", json.NewDecoder(res.Body).Decode(v)
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	res := values[:0]
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
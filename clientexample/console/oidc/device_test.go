@@ -0,0 +1,194 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testIssuer answers token requests of the device flow with a scripted list of OAuth errors,
+// an empty error issues the tokens
+type testIssuer struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	responses []string
+	polls     []time.Time
+}
+
+func newTestIssuer(t *testing.T, responses ...string) *testIssuer {
+	t.Helper()
+
+	issuer := &testIssuer{responses: responses}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"device_authorization_endpoint": issuer.server.URL + "/device",
+			"token_endpoint":                issuer.server.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("client_id") != "chat" || r.PostFormValue("scope") != "openid profile" {
+			http.Error(w, "unexpected device request", http.StatusBadRequest)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-1",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": issuer.server.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+
+		issuer.polls = append(issuer.polls, time.Now())
+
+		if r.PostFormValue("grant_type") != deviceCodeGrant || r.PostFormValue("device_code") != "device-1" || len(issuer.responses) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		response := issuer.responses[0]
+		issuer.responses = issuer.responses[1:]
+
+		if response != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": response})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"id_token":     idToken(map[string]string{"sub": "1234", "preferred_username": "alice"}),
+			"access_token": "access-token",
+		})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *testIssuer) config() Config {
+	return Config{
+		Issuer:        i.server.URL,
+		ClientID:      "chat",
+		Scopes:        []string{"profile"},
+		UsernameClaim: "preferred_username",
+	}
+}
+
+func (i *testIssuer) pollTimes() []time.Time {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return append([]time.Time(nil), i.polls...)
+}
+
+// idToken returns an unsigned JWT of claims, the client does not verify it
+func idToken(claims map[string]string) string {
+	payload, _ := json.Marshal(claims)
+
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+// fastPolling shortens the polling intervals for the test
+func fastPolling(t *testing.T) {
+	unit, increase := intervalUnit, slowDownIncrease
+	intervalUnit, slowDownIncrease = 10*time.Millisecond, 50*time.Millisecond
+
+	t.Cleanup(func() { intervalUnit, slowDownIncrease = unit, increase })
+}
+
+func TestDeviceLoginPollsUntilApproved(t *testing.T) {
+	fastPolling(t)
+	issuer := newTestIssuer(t, "authorization_pending", "slow_down", "")
+
+	var shown DeviceCode
+	login, err := DeviceLogin(context.Background(), issuer.config(), func(code DeviceCode) { shown = code })
+	if err != nil {
+		t.Fatalf("DeviceLogin: %v", err)
+	}
+
+	if shown.UserCode != "ABCD-EFGH" || !strings.HasSuffix(shown.VerificationURI, "/activate") {
+		t.Fatalf("shown code = %+v, want ABCD-EFGH at /activate", shown)
+	}
+
+	if login.Username != "alice" || !strings.HasPrefix(login.Token, "e30.") {
+		t.Fatalf("login = %+v, want the ID token of alice", login)
+	}
+
+	polls := issuer.pollTimes()
+	if len(polls) != 3 {
+		t.Fatalf("token endpoint polled %d times, want 3", len(polls))
+	}
+
+	// slow_down adds to the interval of the following polls
+	if gap := polls[2].Sub(polls[1]); gap < intervalUnit+slowDownIncrease {
+		t.Fatalf("poll after slow_down came after %v, want at least %v", gap, intervalUnit+slowDownIncrease)
+	}
+}
+
+func TestDeviceLoginUsesAccessToken(t *testing.T) {
+	fastPolling(t)
+	issuer := newTestIssuer(t, "")
+
+	config := issuer.config()
+	config.UseAccessToken = true
+
+	login, err := DeviceLogin(context.Background(), config, func(DeviceCode) {})
+	if err != nil {
+		t.Fatalf("DeviceLogin: %v", err)
+	}
+
+	if login.Token != "access-token" || login.Username != "alice" {
+		t.Fatalf("login = %+v, want the access token of alice", login)
+	}
+}
+
+func TestDeviceLoginFails(t *testing.T) {
+	fastPolling(t)
+
+	for _, response := range []string{"access_denied", "expired_token", "invalid_grant"} {
+		issuer := newTestIssuer(t, "authorization_pending", response)
+
+		_, err := DeviceLogin(context.Background(), issuer.config(), func(DeviceCode) {})
+		if err == nil {
+			t.Errorf("DeviceLogin succeeded after %s", response)
+		}
+
+		if polls := len(issuer.pollTimes()); polls != 2 {
+			t.Errorf("token endpoint polled %d times after %s, want 2", polls, response)
+		}
+	}
+}
+
+func TestDeviceLoginStopsWithContext(t *testing.T) {
+	fastPolling(t)
+	pending := make([]string, 100)
+	for i := range pending {
+		pending[i] = "authorization_pending"
+	}
+
+	issuer := newTestIssuer(t, pending...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+	defer cancel()
+
+	_, err := DeviceLogin(ctx, issuer.config(), func(DeviceCode) {})
+	if err == nil {
+		t.Fatal("DeviceLogin succeeded after the context ended")
+	}
+}
@@ -6,6 +6,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -21,6 +28,8 @@ const (
 	JoinGroupChat   option = "Join a group chat"
 	LeaveGroupChat  option = "Leave a group chat"
 	SendMessage     option = "Send a message"
+	SetStatus       option = "Set status"
+	SendFile        option = "Send a file"
 )
 
 var selectHelp = "Press down/up arrow to move cursor. Press enter to select option"
@@ -31,10 +40,25 @@ func (o option) String() string {
 
 type optionExecutor func(ctx context.Context) error
 
+var ackTimeout = time.Second * 5
+
+const (
+	historySize     = 10
+	typingRefresh   = time.Second * 4
+	uploadChunkSize = 32 * 1024
+)
+
 type Prompter struct {
 	userName       string
 	client         pb.ChatServiceClient
+	stream         pb.ChatService_ChatClient
+	sendMu         sync.Mutex
+	acks           chan *pb.SendAck
+	notices        chan string
+	lastEventID    int
 	channelCache   []*pb.Channel
+	unreadCache    map[string]int64
+	presenceCache  map[string]*pb.Presence
 	cacheDuration  time.Duration
 	cacheExpiresAt time.Time
 }
@@ -43,15 +67,19 @@ func NewPrompter(client pb.ChatServiceClient, username string) *Prompter {
 	return &Prompter{
 		userName:      username,
 		client:        client,
+		acks:          make(chan *pb.SendAck, 16),
+		notices:       make(chan string, 100),
 		cacheDuration: time.Second * 10,
 	}
 }
 
 func (p *Prompter) Run(ctx context.Context) error {
-	msgChan := make(chan *pb.Message)
-	defer close(msgChan)
+	msgChan := make(chan *pb.Message, 100)
 
-	go p.connect(ctx, msgChan)
+	err := p.connect(ctx, msgChan)
+	if err != nil {
+		return err
+	}
 
 	for {
 		// initial options
@@ -65,6 +93,7 @@ func (p *Prompter) Run(ctx context.Context) error {
 			return err
 		}
 
+		p.printNotices()
 		p.checkNewMessage(ctx, msgChan)
 	}
 }
@@ -72,16 +101,13 @@ func (p *Prompter) Run(ctx context.Context) error {
 func (p *Prompter) checkNewMessage(ctx context.Context, msgChan <-chan *pb.Message) {
 	select {
 	case msg := <-msgChan:
-		sender := "@" + msg.GetSender()
-		chn := msg.GetChannel()
 		senderName := msg.GetSender()
-		if chn.GetType() == pb.ChannelType_GROUP {
-			senderName = chn.GetName()
-			sender = fmt.Sprintf("group %s (%s)", chn.GetName(), sender)
+		if msg.GetChannel().GetType() == pb.ChannelType_GROUP {
+			senderName = msg.GetChannel().GetName()
 		}
 
 		msgWithQuestion := fmt.Sprintf("New message from %s: --> %s\nDo you want to reply?",
-			sender, msg.GetMessage())
+			senderText(msg), messageText(msg))
 
 		reply := false
 		err := survey.AskOne(&survey.Confirm{
@@ -103,36 +129,269 @@ func (p *Prompter) checkNewMessage(ctx context.Context, msgChan <-chan *pb.Messa
 	}
 }
 
-func (p *Prompter) connect(ctx context.Context, msgChan chan<- *pb.Message) {
-	stream, err := p.client.Connect(ctx, &pb.ConnectRequest{
-		Username: p.userName,
-	})
+// connect opens the Chat stream and waits until the server answers a ping,
+// so the session is registered before any other call is made
+func (p *Prompter) connect(ctx context.Context, msgChan chan<- *pb.Message) error {
+	stream, err := p.client.Chat(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+
+	p.stream = stream
 
+	ready := make(chan error, 1)
+	go p.receive(msgChan, ready)
+
+	err = p.send(&pb.ClientEvent{
+		Event: &pb.ClientEvent_Control{
+			Control: &pb.Control{
+				Type: pb.ControlType_PING,
+			},
+		},
+	})
 	if err != nil {
-		log.Printf("failed to connect: %v", err)
-		return
+		return fmt.Errorf("failed to connect: %w", err)
 	}
 
-	waitC := make(chan struct{})
-	defer close(waitC)
+	select {
+	case err = <-ready:
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
 
-	go func() {
-		for {
-			msg, err := stream.Recv()
-			if err == io.EOF {
-				close(waitC)
+		return nil
+	case <-time.After(ackTimeout):
+		return errors.New("failed to connect: timeout")
+	}
+}
+
+// receive dispatches server events until the stream is closed
+func (p *Prompter) receive(msgChan chan<- *pb.Message, ready chan<- error) {
+	connected := false
+
+	for {
+		event, err := p.stream.Recv()
+		if err != nil {
+			if !connected {
+				ready <- err
 				return
 			}
-			if err != nil {
+
+			if err != io.EOF {
 				log.Printf("failed to receive message: %v", err)
-				return
 			}
 
-			msgChan <- msg
+			return
+		}
+
+		switch e := event.GetEvent().(type) {
+		case *pb.ServerEvent_Message:
+			err = p.ack(e.Message)
+			if err != nil {
+				log.Printf("failed to ack message: %v", err)
+			}
+
+			// the receiver must not block, acks and pongs are read by it as well
+			select {
+			case msgChan <- e.Message:
+			default:
+				p.notify(fmt.Sprintf("New message from %s: %s", senderText(e.Message), messageText(e.Message)))
+			}
+		case *pb.ServerEvent_Receipt:
+			p.notifyReceipt(e.Receipt)
+		case *pb.ServerEvent_Typing:
+			p.notifyTyping(e.Typing)
+		case *pb.ServerEvent_Update:
+			p.notifyUpdate(e.Update)
+		case *pb.ServerEvent_Reaction:
+			p.notifyReaction(e.Reaction)
+		case *pb.ServerEvent_Presence:
+			p.notify(fmt.Sprintf("@%s is %s", e.Presence.GetUser(), presenceText(e.Presence)))
+		case *pb.ServerEvent_SendAck:
+			select {
+			case p.acks <- e.SendAck:
+			default:
+				// nobody waits for acks of timed out sends anymore
+			}
+		case *pb.ServerEvent_Control:
+			if !connected && e.Control.GetType() == pb.ControlType_PONG {
+				connected = true
+				ready <- nil
+			}
+		}
+	}
+}
+
+// send sends event on the Chat stream, the stream allows one sender at a time
+func (p *Prompter) send(event *pb.ClientEvent) error {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+
+	return p.stream.Send(event)
+}
+
+// ack tells the server msg was received so that it is not redelivered
+func (p *Prompter) ack(msg *pb.Message) error {
+	return p.send(&pb.ClientEvent{
+		Event: &pb.ClientEvent_Ack{
+			Ack: &pb.AckMessagesRequest{
+				MessageIds: []string{msg.GetId()},
+			},
+		},
+	})
+}
+
+func (p *Prompter) notifyReceipt(receipt *pb.Receipt) {
+	if receipt.GetType() == pb.ReceiptType_READ {
+		p.notify(fmt.Sprintf("@%s read your messages", receipt.GetUser()))
+		return
+	}
+
+	p.notify(fmt.Sprintf("Message to %s delivered to @%s", receipt.GetChannel().GetName(), receipt.GetUser()))
+}
+
+func (p *Prompter) notifyTyping(typing *pb.Typing) {
+	if !typing.GetTyping() {
+		return
+	}
+
+	if typing.GetChannel().GetType() == pb.ChannelType_GROUP {
+		p.notify(fmt.Sprintf("@%s is typing in group %s...", typing.GetUser(), typing.GetChannel().GetName()))
+		return
+	}
+
+	p.notify(fmt.Sprintf("@%s is typing...", typing.GetUser()))
+}
+
+func (p *Prompter) notifyUpdate(update *pb.MessageUpdate) {
+	msg := update.GetMessage()
+	where := ""
+	if msg.GetChannel().GetType() == pb.ChannelType_GROUP {
+		where = " in group " + msg.GetChannel().GetName()
+	}
+
+	if update.GetType() == pb.UpdateType_DELETED {
+		p.notify(fmt.Sprintf("A message of @%s%s was deleted", msg.GetSender(), where))
+		return
+	}
+
+	p.notify(fmt.Sprintf("@%s edited a message%s: %s", msg.GetSender(), where, msg.GetMessage()))
+}
+
+func (p *Prompter) notifyReaction(reaction *pb.ReactionUpdate) {
+	if !reaction.GetAdded() || reaction.GetUser() == p.userName {
+		return
+	}
+
+	where := ""
+	if reaction.GetChannel().GetType() == pb.ChannelType_GROUP {
+		where = " in group " + reaction.GetChannel().GetName()
+	}
+
+	p.notify(fmt.Sprintf("@%s reacted %s to a message%s", reaction.GetUser(), reaction.GetEmoji(), where))
+}
+
+// reactionSummary returns the reactions as emoji followed by their count, e.g. "👍 2  🎉 1"
+func reactionSummary(reactions []*pb.Reaction) string {
+	parts := make([]string, 0, len(reactions))
+	for _, r := range reactions {
+		parts = append(parts, fmt.Sprintf("%s %d", r.GetEmoji(), r.GetCount()))
+	}
+
+	return strings.Join(parts, "  ")
+}
+
+// senderText returns the sender of msg as shown to the user, with the group of group messages
+func senderText(msg *pb.Message) string {
+	if msg.GetChannel().GetType() == pb.ChannelType_GROUP {
+		return fmt.Sprintf("group %s (@%s)", msg.GetChannel().GetName(), msg.GetSender())
+	}
+
+	return "@" + msg.GetSender()
+}
+
+// messageText returns the text of msg as shown to the user
+func messageText(msg *pb.Message) string {
+	if msg.GetDeleted() {
+		return "[deleted]"
+	}
+
+	if msg.GetSystem() != nil {
+		return "* " + msg.GetMessage()
+	}
+
+	if msg.GetEditTime() != nil {
+		return msg.GetMessage() + " (edited)"
+	}
+
+	return msg.GetMessage()
+}
+
+// notify keeps text to be printed between prompts
+func (p *Prompter) notify(text string) {
+	select {
+	case p.notices <- text:
+	default:
+		// notices are informational, drop them when nobody looks
+	}
+}
+
+// printNotices prints the notices received since the last call
+func (p *Prompter) printNotices() {
+	for {
+		select {
+		case text := <-p.notices:
+			fmt.Println(text)
+		default:
+			return
+		}
+	}
+}
+
+// keepTyping shows the user as typing in channel until the returned func is called.
+// The typing state is refreshed before the server expires it.
+func (p *Prompter) keepTyping(channel string) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(typingRefresh)
+		defer ticker.Stop()
+
+		for {
+			p.setTyping(channel, true)
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
 		}
 	}()
 
-	<-waitC
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// setTyping tells the other participants of channel whether the user is typing
+func (p *Prompter) setTyping(channel string, typing bool) {
+	err := p.send(&pb.ClientEvent{
+		Event: &pb.ClientEvent_Typing{
+			Typing: &pb.Typing{
+				Channel: &pb.Channel{
+					Name: channel,
+				},
+				Typing: typing,
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("failed to send typing: %v", err)
+	}
 }
 
 func (p *Prompter) listChannels(ctx context.Context) error {
@@ -142,14 +401,139 @@ func (p *Prompter) listChannels(ctx context.Context) error {
 	}
 
 	for _, channel := range channels {
-		fmt.Println(fmt.Sprintf("- %s(%s)", channel.GetName(), channel.GetType()))
+		line := fmt.Sprintf("- %s(%s)", channel.GetName(), channel.GetType())
+		if presence, ok := p.presenceCache[channel.GetName()]; ok {
+			line += " " + presenceText(presence)
+		}
+
+		if unread := p.unreadCache[channel.GetName()]; unread > 0 {
+			line += fmt.Sprintf(" [%d unread]", unread)
+		}
+
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+func (p *Prompter) setStatus(ctx context.Context) error {
+	statuses := map[string]pb.PresenceStatus{
+		"Online":         pb.PresenceStatus_ONLINE,
+		"Away":           pb.PresenceStatus_AWAY,
+		"Do not disturb": pb.PresenceStatus_DO_NOT_DISTURB,
+	}
+
+	selected := ""
+	err := survey.AskOne(&survey.Select{
+		Message: "Select status",
+		Options: []string{"Online", "Away", "Do not disturb"},
+		Help:    selectHelp,
+	}, &selected)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.client.SetPresence(ctx, &pb.SetPresenceRequest{
+		Status: statuses[selected],
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nStatus set to %s\n", selected)
+
+	return nil
+}
+
+// presenceText describes presence as "online", "away", "do not disturb" or "offline, last seen ..."
+func presenceText(presence *pb.Presence) string {
+	switch presence.GetStatus() {
+	case pb.PresenceStatus_ONLINE:
+		return "online"
+	case pb.PresenceStatus_AWAY:
+		return "away"
+	case pb.PresenceStatus_DO_NOT_DISTURB:
+		return "do not disturb"
+	}
+
+	if presence.GetLastSeen() == nil {
+		return "offline"
+	}
+
+	return "offline, last seen " + presence.GetLastSeen().AsTime().Local().Format(time.Stamp)
+}
+
+func (p *Prompter) createGroup(ctx context.Context) error {
+	name, err := p.inputGroupName("Group name:")
+	if err != nil {
+		return err
 	}
 
+	_, err = p.client.CreateGroupChat(ctx, &pb.CreateGroupChatRequest{
+		ChannelName: name,
+	})
+	if err != nil {
+		fmt.Printf("\nFailed to create group %s: %v\n", name, err)
+		return nil
+	}
+
+	p.expireChannelCache()
+	fmt.Printf("\nGroup %s created\n", name)
+
 	return nil
 }
 
 func (p *Prompter) joinGroup(ctx context.Context) error {
-	return errors.New("not implemented")
+	name, err := p.askGroupOptions(ctx)
+	if err != nil || name == "" {
+		return err
+	}
+
+	_, err = p.client.JoinGroupChat(ctx, &pb.JoinGroupChatRequest{
+		ChannelName: name,
+	})
+	if err != nil {
+		fmt.Printf("\nFailed to join group %s: %v\n", name, err)
+		return nil
+	}
+
+	p.expireChannelCache()
+	fmt.Printf("\nJoined group %s\n", name)
+
+	return nil
+}
+
+func (p *Prompter) leaveGroup(ctx context.Context) error {
+	name, err := p.askGroupOptions(ctx)
+	if err != nil || name == "" {
+		return err
+	}
+
+	_, err = p.client.LeaveGroupChat(ctx, &pb.LeaveGroupChatRequest{
+		ChannelName: name,
+	})
+	if err != nil {
+		fmt.Printf("\nFailed to leave group %s: %v\n", name, err)
+		return nil
+	}
+
+	p.expireChannelCache()
+	fmt.Printf("\nLeft group %s\n", name)
+
+	return nil
+}
+
+func (p *Prompter) inputGroupName(message string) (string, error) {
+	name := ""
+	err := survey.AskOne(&survey.Input{
+		Message: message,
+	}, &name, survey.WithValidator(survey.Required))
+
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
 }
 
 func (p *Prompter) sendMessage(ctx context.Context) error {
@@ -158,38 +542,266 @@ func (p *Prompter) sendMessage(ctx context.Context) error {
 		return err
 	}
 
+	err = p.showHistory(ctx, channel)
+	if err != nil {
+		fmt.Printf("\nFailed to load history: %v\n", err)
+	}
+
 	return p.inputMessageForChannel(ctx, channel)
 }
 
+// showHistory prints the most recent messages of the selected channel
+func (p *Prompter) showHistory(ctx context.Context, name string) error {
+	channels, err := p.getChannelCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		if channel.GetName() != name {
+			continue
+		}
+
+		res, err := p.client.GetHistory(ctx, &pb.GetHistoryRequest{
+			Channel:  channel,
+			PageSize: historySize,
+		})
+		if err != nil {
+			return err
+		}
+
+		messages := res.GetMessages()
+		for _, msg := range messages {
+			reply := ""
+			if msg.GetParentId() != "" {
+				reply = "↳ "
+			}
+
+			replies := ""
+			if msg.GetReplyCount() > 0 {
+				replies = fmt.Sprintf(" [%d replies]", msg.GetReplyCount())
+			}
+
+			fmt.Printf("[%s] %s@%s: %s%s\n", msg.GetTime().AsTime().Local().Format(time.Kitchen),
+				reply, msg.GetSender(), messageText(msg), replies)
+
+			attachments := msg.GetAttachments()
+			if content := msg.GetAttachment(); content != nil {
+				attachments = append([]*pb.Attachment{content.GetAttachment()}, attachments...)
+			}
+
+			for _, attachment := range attachments {
+				fmt.Printf("    [file] %s (%s, %d bytes) id %s\n", attachment.GetName(),
+					attachment.GetContentType(), attachment.GetSize(), attachment.GetId())
+			}
+
+			if summary := reactionSummary(msg.GetReactions()); summary != "" {
+				fmt.Printf("    %s\n", summary)
+			}
+		}
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		_, err = p.client.MarkRead(ctx, &pb.MarkReadRequest{
+			Channel:       channel,
+			UpToMessageId: messages[len(messages)-1].GetId(),
+		})
+		if err != nil {
+			return err
+		}
+
+		p.expireChannelCache()
+
+		return nil
+	}
+
+	return nil
+}
+
 func (p *Prompter) inputMessageForChannel(ctx context.Context, channel string) error {
+	stopTyping := p.keepTyping(channel)
+
 	msg := ""
 	err := survey.AskOne(&survey.Input{
 		Message: "Message:",
 		Help:    "message for " + channel,
 	}, &msg)
 
-	if err != nil {
-		return err
-	}
+	stopTyping()
 
-	stream, err := p.client.SendMessage(ctx)
 	if err != nil {
+		p.setTyping(channel, false)
 		return err
 	}
 
-	req := &pb.SendMessageRequest{
+	return p.sendRequest(&pb.SendMessageRequest{
 		Receiver: channel,
 		Message:  msg,
+	})
+}
+
+// sendRequest sends req over the Chat stream and waits until the server acknowledges it
+func (p *Prompter) sendRequest(req *pb.SendMessageRequest) error {
+	p.lastEventID++
+	id := strconv.Itoa(p.lastEventID)
+
+	err := p.send(&pb.ClientEvent{
+		Id: id,
+		Event: &pb.ClientEvent_Send{
+			Send: req,
+		},
+	})
+	if err != nil {
+		return err
 	}
 
-	err = stream.Send(req)
+	ack, err := p.waitAck(id)
 	if err != nil {
 		return err
 	}
 
+	if ack.GetError() != "" {
+		fmt.Printf("\nMessage not sent: %s\n", ack.GetError())
+		return nil
+	}
+
 	fmt.Println("\nMessage sent")
 
-	return stream.CloseSend()
+	return nil
+}
+
+func (p *Prompter) sendFile(ctx context.Context) error {
+	name, err := p.askChannelOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	channels, err := p.getChannelCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	var channel *pb.Channel
+	for _, c := range channels {
+		if c.GetName() == name {
+			channel = c
+		}
+	}
+
+	if channel == nil {
+		return nil
+	}
+
+	path := ""
+	err = survey.AskOne(&survey.Input{
+		Message: "File path:",
+	}, &path, survey.WithValidator(survey.Required))
+	if err != nil {
+		return err
+	}
+
+	attachment, err := p.upload(ctx, channel, path)
+	if err != nil {
+		fmt.Printf("\nFile not uploaded: %v\n", err)
+		return nil
+	}
+
+	msg := ""
+	err = survey.AskOne(&survey.Input{
+		Message: "Message:",
+		Help:    "optional message sent with " + attachment.GetName(),
+	}, &msg)
+	if err != nil {
+		return err
+	}
+
+	return p.sendRequest(&pb.SendMessageRequest{
+		Receiver: channel.GetName(),
+		Content: &pb.SendMessageRequest_Attachment{
+			Attachment: &pb.AttachmentContent{
+				Attachment: &pb.Attachment{Id: attachment.GetId()},
+				Caption:    msg,
+			},
+		},
+	})
+}
+
+// upload sends the file at path to channel in chunks
+func (p *Prompter) upload(ctx context.Context, channel *pb.Channel, path string) (*pb.Attachment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := f.ReadAt(head, 0)
+		contentType = http.DetectContentType(head[:n])
+	}
+
+	stream, err := p.client.UploadAttachment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.UploadAttachmentRequest{
+		Data: &pb.UploadAttachmentRequest_Info{
+			Info: &pb.Attachment{
+				Channel:     channel,
+				Name:        filepath.Base(path),
+				ContentType: contentType,
+				Size:        stat.Size(),
+			},
+		},
+	})
+
+	buf := make([]byte, uploadChunkSize)
+	for err == nil {
+		var n int
+		n, err = f.Read(buf)
+		if n == 0 {
+			continue
+		}
+
+		// a rejected upload is reported by CloseAndRecv with the server error
+		if stream.Send(&pb.UploadAttachmentRequest{
+			Data: &pb.UploadAttachmentRequest_Chunk{Chunk: buf[:n]},
+		}) != nil {
+			break
+		}
+	}
+
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return stream.CloseAndRecv()
+}
+
+// waitAck waits for the server acknowledgement of the send frame id
+func (p *Prompter) waitAck(id string) (*pb.SendAck, error) {
+	timeout := time.After(ackTimeout)
+
+	for {
+		select {
+		case ack := <-p.acks:
+			if ack.GetId() == id {
+				return ack, nil
+			}
+		case <-timeout:
+			return nil, errors.New("no acknowledgement for sent message")
+		}
+	}
 }
 
 func (p *Prompter) askOptions(ctx context.Context) (optionExecutor, error) {
@@ -215,10 +827,18 @@ func (p *Prompter) getOptionExecutor(op option) optionExecutor {
 	switch op {
 	case ListAllChannels:
 		return p.listChannels
+	case CreateGroupChat:
+		return p.createGroup
 	case JoinGroupChat:
 		return p.joinGroup
+	case LeaveGroupChat:
+		return p.leaveGroup
 	case SendMessage:
 		return p.sendMessage
+	case SetStatus:
+		return p.setStatus
+	case SendFile:
+		return p.sendFile
 	default:
 		return nil
 	}
@@ -250,6 +870,42 @@ func (p *Prompter) askChannelOptions(ctx context.Context) (string, error) {
 
 }
 
+func (p *Prompter) askGroupOptions(ctx context.Context) (string, error) {
+	channels, err := p.getChannelCache(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	groupOptions := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if channel.GetType() == pb.ChannelType_GROUP {
+			groupOptions = append(groupOptions, channel.GetName())
+		}
+	}
+
+	if len(groupOptions) == 0 {
+		fmt.Println("\nNo groups available")
+		return "", nil
+	}
+
+	group := ""
+	err = survey.AskOne(&survey.Select{
+		Message: "Select group",
+		Options: groupOptions,
+		Help:    selectHelp,
+	}, &group)
+
+	if err != nil {
+		return "", err
+	}
+
+	return group, nil
+}
+
+func (p *Prompter) expireChannelCache() {
+	p.cacheExpiresAt = time.Time{}
+}
+
 func (p *Prompter) getChannelCache(ctx context.Context) ([]*pb.Channel, error) {
 	if len(p.channelCache) != 0 && time.Now().Before(p.cacheExpiresAt) {
 		return p.channelCache, nil
@@ -262,6 +918,8 @@ func (p *Prompter) getChannelCache(ctx context.Context) ([]*pb.Channel, error) {
 
 	channels := res.GetChannels()
 	p.channelCache = channels
+	p.unreadCache = res.GetUnreadCounts()
+	p.presenceCache = res.GetPresence()
 	p.cacheExpiresAt = time.Now().Add(p.cacheDuration)
 
 	return channels, nil
@@ -272,7 +930,7 @@ func getRootOptions() *survey.Select {
 		Message: "Select an option",
 		Options: []string{
 			ListAllChannels.String(), CreateGroupChat.String(), JoinGroupChat.String(),
-			LeaveGroupChat.String(), SendMessage.String(),
+			LeaveGroupChat.String(), SendMessage.String(), SendFile.String(), SetStatus.String(),
 		},
 		Default: ListAllChannels.String(),
 		Help:    selectHelp,
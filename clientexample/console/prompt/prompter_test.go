@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+// fakeChatStream replays events to the client and records what it sends
+type fakeChatStream struct {
+	grpc.ClientStream
+
+	events chan *pb.ServerEvent
+
+	mu   sync.Mutex
+	sent []*pb.ClientEvent
+}
+
+func (s *fakeChatStream) Recv() (*pb.ServerEvent, error) {
+	event, ok := <-s.events
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return event, nil
+}
+
+func (s *fakeChatStream) Send(event *pb.ClientEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, event)
+
+	return nil
+}
+
+func TestReceiveDoesNotBlockOnUnreadMessages(t *testing.T) {
+	stream := &fakeChatStream{events: make(chan *pb.ServerEvent)}
+	p := NewPrompter(nil, "alice")
+	p.stream = stream
+
+	msgChan := make(chan *pb.Message, 1)
+	go p.receive(msgChan, make(chan error, 1))
+
+	// nobody reads msgChan while the user is busy with a prompt
+	for i := 0; i < 10; i++ {
+		stream.events <- &pb.ServerEvent{
+			Event: &pb.ServerEvent_Message{
+				Message: &pb.Message{Id: "m", Sender: "bob", Message: "hello"},
+			},
+		}
+	}
+
+	stream.events <- &pb.ServerEvent{
+		Event: &pb.ServerEvent_SendAck{
+			SendAck: &pb.SendAck{Id: "1"},
+		},
+	}
+
+	close(stream.events)
+
+	select {
+	case ack := <-p.acks:
+		if ack.GetId() != "1" {
+			t.Fatalf("ack = %v, want 1", ack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("send ack was not received behind unread messages")
+	}
+
+	if len(msgChan) != 1 {
+		t.Fatalf("%d messages queued, want 1", len(msgChan))
+	}
+
+	if len(p.notices) != 9 {
+		t.Fatalf("%d notices, want the 9 messages which did not fit", len(p.notices))
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if len(stream.sent) != 10 {
+		t.Fatalf("%d messages acked, want 10", len(stream.sent))
+	}
+}
@@ -0,0 +1,119 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config is the TLS configuration of the client
+type Config struct {
+	// CAFile verifies the server certificate, the system roots are used when empty
+	CAFile string
+	// CertFile and KeyFile are the client certificate presented for mutual TLS, optional
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name verified in the server certificate
+	ServerName string
+}
+
+// New returns the tls.Config of c. The client certificate is loaded again on handshakes
+// after its files changed.
+func New(c Config) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no PEM certificates found", c.CAFile)
+		}
+	}
+
+	if c.CertFile == "" && c.KeyFile == "" {
+		return config, nil
+	}
+
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, fmt.Errorf("both a client certificate and key are required")
+	}
+
+	pair := &keyPair{
+		certFile: c.CertFile,
+		keyFile:  c.KeyFile,
+	}
+
+	err := pair.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return pair.current(), nil
+	}
+
+	return config, nil
+}
+
+// keyPair is a client certificate reloaded when its files change
+type keyPair struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// current returns the certificate after loading changed files, the previous one is kept on errors
+func (p *keyPair) current() *tls.Certificate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.reload()
+	if err != nil {
+		log.Printf("failed to reload client certificate, keeping the previous one: %v", err)
+	}
+
+	return p.cert
+}
+
+// reload loads the files when the newer of their modification times changed
+func (p *keyPair) reload() error {
+	var modTime time.Time
+	for _, file := range []string{p.certFile, p.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+
+	if p.cert != nil && modTime.Equal(p.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		return err
+	}
+
+	p.cert = &cert
+	p.modTime = modTime
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/vitthalaa/go-grpc-chat/server/certs/certstest"
+)
+
+// serve accepts TLS connections with the server certificate of ca until the test ends.
+// Client certificates are required when requireClient is set, their common names are sent on
+// the returned channel, an empty name for a failed handshake.
+func serve(t *testing.T, ca *certstest.CA, requireClient bool) (string, <-chan string) {
+	t.Helper()
+
+	cert, key := ca.Issue(t, "server", x509.ExtKeyUsageServerAuth)
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tls.Config{Certificates: []tls.Certificate{pair}}
+	if requireClient {
+		config.ClientCAs = x509.NewCertPool()
+		config.ClientCAs.AppendCertsFromPEM(ca.PEM)
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { lis.Close() })
+
+	clients := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			tlsConn := conn.(*tls.Conn)
+
+			name := ""
+			if tlsConn.Handshake() == nil {
+				if peers := tlsConn.ConnectionState().PeerCertificates; len(peers) > 0 {
+					name = peers[0].Subject.CommonName
+				}
+
+				_, _ = conn.Write([]byte("ok"))
+			}
+
+			clients <- name
+			conn.Close()
+		}
+	}()
+
+	return lis.Addr().String(), clients
+}
+
+func dial(addr string, config *tls.Config) error {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	_, err = io.ReadAll(conn)
+
+	return err
+}
+
+func TestNewVerifiesServer(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t)
+	certstest.WriteFile(t, filepath.Join(dir, "ca.crt"), ca.PEM)
+
+	addr, _ := serve(t, ca, false)
+
+	config, err := New(Config{CAFile: filepath.Join(dir, "ca.crt"), ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	err = dial(addr, config)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	// a server of another CA is not trusted
+	addr, _ = serve(t, certstest.NewCA(t), false)
+
+	err = dial(addr, config)
+	if err == nil {
+		t.Fatal("dial to a server of an unknown CA succeeded")
+	}
+}
+
+func TestNewPresentsReloadedClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	certstest.WriteFile(t, caFile, ca.PEM)
+	cert, key := ca.Issue(t, "alice", x509.ExtKeyUsageClientAuth)
+	certstest.WriteFile(t, certFile, cert)
+	certstest.WriteFile(t, keyFile, key)
+
+	addr, clients := serve(t, ca, true)
+
+	noCert, err := New(Config{CAFile: caFile, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_ = dial(addr, noCert)
+	if name := <-clients; name != "" {
+		t.Fatalf("server accepted client %q without a certificate", name)
+	}
+
+	config, err := New(Config{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	err = dial(addr, config)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	if name := <-clients; name != "alice" {
+		t.Fatalf("server saw client %q, want alice", name)
+	}
+
+	cert, key = ca.Issue(t, "bob", x509.ExtKeyUsageClientAuth)
+	certstest.WriteFile(t, certFile, cert)
+	certstest.WriteFile(t, keyFile, key)
+
+	err = dial(addr, config)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	if name := <-clients; name != "bob" {
+		t.Fatalf("server saw client %q after rewriting the files, want bob", name)
+	}
+}
+
+func TestNewRejectsIncompleteConfig(t *testing.T) {
+	dir := t.TempDir()
+	certstest.WriteFile(t, filepath.Join(dir, "empty.crt"), []byte("no certificates"))
+
+	_, err := New(Config{CAFile: filepath.Join(dir, "empty.crt")})
+	if err == nil {
+		t.Error("New accepted a CA file without certificates")
+	}
+
+	_, err = New(Config{CertFile: filepath.Join(dir, "client.crt")})
+	if err == nil {
+		t.Error("New accepted a client certificate without key")
+	}
+}
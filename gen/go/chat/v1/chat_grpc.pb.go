@@ -20,24 +20,74 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	ChatService_Connect_FullMethodName         = "/chat.v1.ChatService/Connect"
-	ChatService_CreateGroupChat_FullMethodName = "/chat.v1.ChatService/CreateGroupChat"
-	ChatService_JoinGroupChat_FullMethodName   = "/chat.v1.ChatService/JoinGroupChat"
-	ChatService_LeaveGroupChat_FullMethodName  = "/chat.v1.ChatService/LeaveGroupChat"
-	ChatService_SendMessage_FullMethodName     = "/chat.v1.ChatService/SendMessage"
-	ChatService_ListChannels_FullMethodName    = "/chat.v1.ChatService/ListChannels"
+	ChatService_Register_FullMethodName           = "/chat.v1.ChatService/Register"
+	ChatService_Login_FullMethodName              = "/chat.v1.ChatService/Login"
+	ChatService_ChangePassword_FullMethodName     = "/chat.v1.ChatService/ChangePassword"
+	ChatService_DeleteAccount_FullMethodName      = "/chat.v1.ChatService/DeleteAccount"
+	ChatService_Connect_FullMethodName            = "/chat.v1.ChatService/Connect"
+	ChatService_CreateGroupChat_FullMethodName    = "/chat.v1.ChatService/CreateGroupChat"
+	ChatService_JoinGroupChat_FullMethodName      = "/chat.v1.ChatService/JoinGroupChat"
+	ChatService_LeaveGroupChat_FullMethodName     = "/chat.v1.ChatService/LeaveGroupChat"
+	ChatService_SendMessage_FullMethodName        = "/chat.v1.ChatService/SendMessage"
+	ChatService_ListChannels_FullMethodName       = "/chat.v1.ChatService/ListChannels"
+	ChatService_GetHistory_FullMethodName         = "/chat.v1.ChatService/GetHistory"
+	ChatService_GetThread_FullMethodName          = "/chat.v1.ChatService/GetThread"
+	ChatService_AckMessages_FullMethodName        = "/chat.v1.ChatService/AckMessages"
+	ChatService_MarkRead_FullMethodName           = "/chat.v1.ChatService/MarkRead"
+	ChatService_SetTyping_FullMethodName          = "/chat.v1.ChatService/SetTyping"
+	ChatService_SetPresence_FullMethodName        = "/chat.v1.ChatService/SetPresence"
+	ChatService_EditMessage_FullMethodName        = "/chat.v1.ChatService/EditMessage"
+	ChatService_DeleteMessage_FullMethodName      = "/chat.v1.ChatService/DeleteMessage"
+	ChatService_AddReaction_FullMethodName        = "/chat.v1.ChatService/AddReaction"
+	ChatService_RemoveReaction_FullMethodName     = "/chat.v1.ChatService/RemoveReaction"
+	ChatService_UploadAttachment_FullMethodName   = "/chat.v1.ChatService/UploadAttachment"
+	ChatService_DownloadAttachment_FullMethodName = "/chat.v1.ChatService/DownloadAttachment"
+	ChatService_Chat_FullMethodName               = "/chat.v1.ChatService/Chat"
 )
 
 // ChatServiceClient is the client API for ChatService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ChatServiceClient interface {
+	// Register creates an account, user names are 3 to 32 letters, digits, '.', '_' or '-'
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Login issues a session token. With the default token authentication all other rpcs but Register
+	// require it as a bearer authorization header, servers running with -auth api-key, mtls, proxy-header
+	// or oidc authenticate calls by their api key, client certificate, proxy header or OIDC token instead
+	// and fail Register and Login with FAILED_PRECONDITION
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	// ChangePassword revokes all tokens of the user and returns a new one
+	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	// DeleteAccount removes the account of the user, its live stream is closed and it leaves all groups
+	DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (ChatService_ConnectClient, error)
 	CreateGroupChat(ctx context.Context, in *CreateGroupChatRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	JoinGroupChat(ctx context.Context, in *JoinGroupChatRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	LeaveGroupChat(ctx context.Context, in *LeaveGroupChatRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	SendMessage(ctx context.Context, opts ...grpc.CallOption) (ChatService_SendMessageClient, error)
 	ListChannels(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListChannelsResponse, error)
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	// GetThread pages through the replies to a message from the oldest to the newest
+	GetThread(ctx context.Context, in *GetThreadRequest, opts ...grpc.CallOption) (*GetThreadResponse, error)
+	// AckMessages acknowledges messages received on a Connect stream
+	AckMessages(ctx context.Context, in *AckMessagesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SetTyping sets the typing state of the user, it is pushed to Chat streams and Connect streams asking for events
+	SetTyping(ctx context.Context, in *Typing, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	SetPresence(ctx context.Context, in *SetPresenceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// EditMessage replaces the text of a text message of the user, the previous text is kept in the revisions
+	EditMessage(ctx context.Context, in *EditMessageRequest, opts ...grpc.CallOption) (*Message, error)
+	// DeleteMessage deletes a message of the user, group admins can delete any message of their group
+	DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	AddReaction(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RemoveReaction(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// UploadAttachment stores a file sent in chunks, the first request carries the attachment info
+	// followed by the content chunks. The returned attachment can be referenced by messages of its channel.
+	UploadAttachment(ctx context.Context, opts ...grpc.CallOption) (ChatService_UploadAttachmentClient, error)
+	// DownloadAttachment sends the attachment info followed by the content chunks
+	DownloadAttachment(ctx context.Context, in *DownloadAttachmentRequest, opts ...grpc.CallOption) (ChatService_DownloadAttachmentClient, error)
+	// Chat combines Connect and SendMessage over one long-lived stream
+	Chat(ctx context.Context, opts ...grpc.CallOption) (ChatService_ChatClient, error)
 }
 
 type chatServiceClient struct {
@@ -48,6 +98,42 @@ func NewChatServiceClient(cc grpc.ClientConnInterface) ChatServiceClient {
 	return &chatServiceClient{cc}
 }
 
+func (c *chatServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_Register_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, ChatService_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, ChatService_ChangePassword_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_DeleteAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *chatServiceClient) Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (ChatService_ConnectClient, error) {
 	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[0], ChatService_Connect_FullMethodName, opts...)
 	if err != nil {
@@ -64,7 +150,7 @@ func (c *chatServiceClient) Connect(ctx context.Context, in *ConnectRequest, opt
 }
 
 type ChatService_ConnectClient interface {
-	Recv() (*Message, error)
+	Recv() (*ConnectResponse, error)
 	grpc.ClientStream
 }
 
@@ -72,8 +158,8 @@ type chatServiceConnectClient struct {
 	grpc.ClientStream
 }
 
-func (x *chatServiceConnectClient) Recv() (*Message, error) {
-	m := new(Message)
+func (x *chatServiceConnectClient) Recv() (*ConnectResponse, error) {
+	m := new(ConnectResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
@@ -118,7 +204,7 @@ func (c *chatServiceClient) SendMessage(ctx context.Context, opts ...grpc.CallOp
 
 type ChatService_SendMessageClient interface {
 	Send(*SendMessageRequest) error
-	CloseAndRecv() (*emptypb.Empty, error)
+	CloseAndRecv() (*SendMessageResponse, error)
 	grpc.ClientStream
 }
 
@@ -130,11 +216,11 @@ func (x *chatServiceSendMessageClient) Send(m *SendMessageRequest) error {
 	return x.ClientStream.SendMsg(m)
 }
 
-func (x *chatServiceSendMessageClient) CloseAndRecv() (*emptypb.Empty, error) {
+func (x *chatServiceSendMessageClient) CloseAndRecv() (*SendMessageResponse, error) {
 	if err := x.ClientStream.CloseSend(); err != nil {
 		return nil, err
 	}
-	m := new(emptypb.Empty)
+	m := new(SendMessageResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
@@ -150,16 +236,236 @@ func (c *chatServiceClient) ListChannels(ctx context.Context, in *emptypb.Empty,
 	return out, nil
 }
 
+func (c *chatServiceClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, ChatService_GetHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) GetThread(ctx context.Context, in *GetThreadRequest, opts ...grpc.CallOption) (*GetThreadResponse, error) {
+	out := new(GetThreadResponse)
+	err := c.cc.Invoke(ctx, ChatService_GetThread_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) AckMessages(ctx context.Context, in *AckMessagesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_AckMessages_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_MarkRead_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) SetTyping(ctx context.Context, in *Typing, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_SetTyping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) SetPresence(ctx context.Context, in *SetPresenceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_SetPresence_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) EditMessage(ctx context.Context, in *EditMessageRequest, opts ...grpc.CallOption) (*Message, error) {
+	out := new(Message)
+	err := c.cc.Invoke(ctx, ChatService_EditMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_DeleteMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) AddReaction(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_AddReaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) RemoveReaction(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ChatService_RemoveReaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) UploadAttachment(ctx context.Context, opts ...grpc.CallOption) (ChatService_UploadAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[2], ChatService_UploadAttachment_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceUploadAttachmentClient{stream}
+	return x, nil
+}
+
+type ChatService_UploadAttachmentClient interface {
+	Send(*UploadAttachmentRequest) error
+	CloseAndRecv() (*Attachment, error)
+	grpc.ClientStream
+}
+
+type chatServiceUploadAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceUploadAttachmentClient) Send(m *UploadAttachmentRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *chatServiceUploadAttachmentClient) CloseAndRecv() (*Attachment, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Attachment)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServiceClient) DownloadAttachment(ctx context.Context, in *DownloadAttachmentRequest, opts ...grpc.CallOption) (ChatService_DownloadAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[3], ChatService_DownloadAttachment_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceDownloadAttachmentClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatService_DownloadAttachmentClient interface {
+	Recv() (*DownloadAttachmentResponse, error)
+	grpc.ClientStream
+}
+
+type chatServiceDownloadAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceDownloadAttachmentClient) Recv() (*DownloadAttachmentResponse, error) {
+	m := new(DownloadAttachmentResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServiceClient) Chat(ctx context.Context, opts ...grpc.CallOption) (ChatService_ChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[4], ChatService_Chat_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceChatClient{stream}
+	return x, nil
+}
+
+type ChatService_ChatClient interface {
+	Send(*ClientEvent) error
+	Recv() (*ServerEvent, error)
+	grpc.ClientStream
+}
+
+type chatServiceChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceChatClient) Send(m *ClientEvent) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *chatServiceChatClient) Recv() (*ServerEvent, error) {
+	m := new(ServerEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ChatServiceServer is the server API for ChatService service.
 // All implementations must embed UnimplementedChatServiceServer
 // for forward compatibility
 type ChatServiceServer interface {
+	// Register creates an account, user names are 3 to 32 letters, digits, '.', '_' or '-'
+	Register(context.Context, *RegisterRequest) (*emptypb.Empty, error)
+	// Login issues a session token. With the default token authentication all other rpcs but Register
+	// require it as a bearer authorization header, servers running with -auth api-key, mtls, proxy-header
+	// or oidc authenticate calls by their api key, client certificate, proxy header or OIDC token instead
+	// and fail Register and Login with FAILED_PRECONDITION
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	// ChangePassword revokes all tokens of the user and returns a new one
+	ChangePassword(context.Context, *ChangePasswordRequest) (*LoginResponse, error)
+	// DeleteAccount removes the account of the user, its live stream is closed and it leaves all groups
+	DeleteAccount(context.Context, *DeleteAccountRequest) (*emptypb.Empty, error)
 	Connect(*ConnectRequest, ChatService_ConnectServer) error
 	CreateGroupChat(context.Context, *CreateGroupChatRequest) (*emptypb.Empty, error)
 	JoinGroupChat(context.Context, *JoinGroupChatRequest) (*emptypb.Empty, error)
 	LeaveGroupChat(context.Context, *LeaveGroupChatRequest) (*emptypb.Empty, error)
 	SendMessage(ChatService_SendMessageServer) error
 	ListChannels(context.Context, *emptypb.Empty) (*ListChannelsResponse, error)
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	// GetThread pages through the replies to a message from the oldest to the newest
+	GetThread(context.Context, *GetThreadRequest) (*GetThreadResponse, error)
+	// AckMessages acknowledges messages received on a Connect stream
+	AckMessages(context.Context, *AckMessagesRequest) (*emptypb.Empty, error)
+	MarkRead(context.Context, *MarkReadRequest) (*emptypb.Empty, error)
+	// SetTyping sets the typing state of the user, it is pushed to Chat streams and Connect streams asking for events
+	SetTyping(context.Context, *Typing) (*emptypb.Empty, error)
+	SetPresence(context.Context, *SetPresenceRequest) (*emptypb.Empty, error)
+	// EditMessage replaces the text of a text message of the user, the previous text is kept in the revisions
+	EditMessage(context.Context, *EditMessageRequest) (*Message, error)
+	// DeleteMessage deletes a message of the user, group admins can delete any message of their group
+	DeleteMessage(context.Context, *DeleteMessageRequest) (*emptypb.Empty, error)
+	AddReaction(context.Context, *ReactionRequest) (*emptypb.Empty, error)
+	RemoveReaction(context.Context, *ReactionRequest) (*emptypb.Empty, error)
+	// UploadAttachment stores a file sent in chunks, the first request carries the attachment info
+	// followed by the content chunks. The returned attachment can be referenced by messages of its channel.
+	UploadAttachment(ChatService_UploadAttachmentServer) error
+	// DownloadAttachment sends the attachment info followed by the content chunks
+	DownloadAttachment(*DownloadAttachmentRequest, ChatService_DownloadAttachmentServer) error
+	// Chat combines Connect and SendMessage over one long-lived stream
+	Chat(ChatService_ChatServer) error
 	mustEmbedUnimplementedChatServiceServer()
 }
 
@@ -167,6 +473,18 @@ type ChatServiceServer interface {
 type UnimplementedChatServiceServer struct {
 }
 
+func (UnimplementedChatServiceServer) Register(context.Context, *RegisterRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedChatServiceServer) Login(context.Context, *LoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedChatServiceServer) ChangePassword(context.Context, *ChangePasswordRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
+}
+func (UnimplementedChatServiceServer) DeleteAccount(context.Context, *DeleteAccountRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
+}
 func (UnimplementedChatServiceServer) Connect(*ConnectRequest, ChatService_ConnectServer) error {
 	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
 }
@@ -185,6 +503,45 @@ func (UnimplementedChatServiceServer) SendMessage(ChatService_SendMessageServer)
 func (UnimplementedChatServiceServer) ListChannels(context.Context, *emptypb.Empty) (*ListChannelsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListChannels not implemented")
 }
+func (UnimplementedChatServiceServer) GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedChatServiceServer) GetThread(context.Context, *GetThreadRequest) (*GetThreadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetThread not implemented")
+}
+func (UnimplementedChatServiceServer) AckMessages(context.Context, *AckMessagesRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AckMessages not implemented")
+}
+func (UnimplementedChatServiceServer) MarkRead(context.Context, *MarkReadRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkRead not implemented")
+}
+func (UnimplementedChatServiceServer) SetTyping(context.Context, *Typing) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTyping not implemented")
+}
+func (UnimplementedChatServiceServer) SetPresence(context.Context, *SetPresenceRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPresence not implemented")
+}
+func (UnimplementedChatServiceServer) EditMessage(context.Context, *EditMessageRequest) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EditMessage not implemented")
+}
+func (UnimplementedChatServiceServer) DeleteMessage(context.Context, *DeleteMessageRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMessage not implemented")
+}
+func (UnimplementedChatServiceServer) AddReaction(context.Context, *ReactionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddReaction not implemented")
+}
+func (UnimplementedChatServiceServer) RemoveReaction(context.Context, *ReactionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveReaction not implemented")
+}
+func (UnimplementedChatServiceServer) UploadAttachment(ChatService_UploadAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadAttachment not implemented")
+}
+func (UnimplementedChatServiceServer) DownloadAttachment(*DownloadAttachmentRequest, ChatService_DownloadAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadAttachment not implemented")
+}
+func (UnimplementedChatServiceServer) Chat(ChatService_ChatServer) error {
+	return status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
 func (UnimplementedChatServiceServer) mustEmbedUnimplementedChatServiceServer() {}
 
 // UnsafeChatServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -198,6 +555,78 @@ func RegisterChatServiceServer(s grpc.ServiceRegistrar, srv ChatServiceServer) {
 	s.RegisterService(&ChatService_ServiceDesc, srv)
 }
 
+func _ChatService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ChangePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_ChangePassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ChangePassword(ctx, req.(*ChangePasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).DeleteAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_DeleteAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).DeleteAccount(ctx, req.(*DeleteAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ChatService_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(ConnectRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -207,7 +636,7 @@ func _ChatService_Connect_Handler(srv interface{}, stream grpc.ServerStream) err
 }
 
 type ChatService_ConnectServer interface {
-	Send(*Message) error
+	Send(*ConnectResponse) error
 	grpc.ServerStream
 }
 
@@ -215,7 +644,7 @@ type chatServiceConnectServer struct {
 	grpc.ServerStream
 }
 
-func (x *chatServiceConnectServer) Send(m *Message) error {
+func (x *chatServiceConnectServer) Send(m *ConnectResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -278,7 +707,7 @@ func _ChatService_SendMessage_Handler(srv interface{}, stream grpc.ServerStream)
 }
 
 type ChatService_SendMessageServer interface {
-	SendAndClose(*emptypb.Empty) error
+	SendAndClose(*SendMessageResponse) error
 	Recv() (*SendMessageRequest, error)
 	grpc.ServerStream
 }
@@ -287,7 +716,7 @@ type chatServiceSendMessageServer struct {
 	grpc.ServerStream
 }
 
-func (x *chatServiceSendMessageServer) SendAndClose(m *emptypb.Empty) error {
+func (x *chatServiceSendMessageServer) SendAndClose(m *SendMessageResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -317,6 +746,259 @@ func _ChatService_ListChannels_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChatService_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_GetThread_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetThreadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetThread(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_GetThread_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetThread(ctx, req.(*GetThreadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_AckMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).AckMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_AckMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).AckMessages(ctx, req.(*AckMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_MarkRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).MarkRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_MarkRead_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).MarkRead(ctx, req.(*MarkReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_SetTyping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Typing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SetTyping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_SetTyping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SetTyping(ctx, req.(*Typing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_SetPresence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPresenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SetPresence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_SetPresence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SetPresence(ctx, req.(*SetPresenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_EditMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EditMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).EditMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_EditMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).EditMessage(ctx, req.(*EditMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_DeleteMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).DeleteMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_DeleteMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).DeleteMessage(ctx, req.(*DeleteMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_AddReaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).AddReaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_AddReaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).AddReaction(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_RemoveReaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).RemoveReaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_RemoveReaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).RemoveReaction(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_UploadAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ChatServiceServer).UploadAttachment(&chatServiceUploadAttachmentServer{stream})
+}
+
+type ChatService_UploadAttachmentServer interface {
+	SendAndClose(*Attachment) error
+	Recv() (*UploadAttachmentRequest, error)
+	grpc.ServerStream
+}
+
+type chatServiceUploadAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceUploadAttachmentServer) SendAndClose(m *Attachment) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *chatServiceUploadAttachmentServer) Recv() (*UploadAttachmentRequest, error) {
+	m := new(UploadAttachmentRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ChatService_DownloadAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadAttachmentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServiceServer).DownloadAttachment(m, &chatServiceDownloadAttachmentServer{stream})
+}
+
+type ChatService_DownloadAttachmentServer interface {
+	Send(*DownloadAttachmentResponse) error
+	grpc.ServerStream
+}
+
+type chatServiceDownloadAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceDownloadAttachmentServer) Send(m *DownloadAttachmentResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChatService_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ChatServiceServer).Chat(&chatServiceChatServer{stream})
+}
+
+type ChatService_ChatServer interface {
+	Send(*ServerEvent) error
+	Recv() (*ClientEvent, error)
+	grpc.ServerStream
+}
+
+type chatServiceChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceChatServer) Send(m *ServerEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *chatServiceChatServer) Recv() (*ClientEvent, error) {
+	m := new(ClientEvent)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ChatService_ServiceDesc is the grpc.ServiceDesc for ChatService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -324,6 +1006,22 @@ var ChatService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "chat.v1.ChatService",
 	HandlerType: (*ChatServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _ChatService_Register_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _ChatService_Login_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _ChatService_ChangePassword_Handler,
+		},
+		{
+			MethodName: "DeleteAccount",
+			Handler:    _ChatService_DeleteAccount_Handler,
+		},
 		{
 			MethodName: "CreateGroupChat",
 			Handler:    _ChatService_CreateGroupChat_Handler,
@@ -340,6 +1038,46 @@ var ChatService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListChannels",
 			Handler:    _ChatService_ListChannels_Handler,
 		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _ChatService_GetHistory_Handler,
+		},
+		{
+			MethodName: "GetThread",
+			Handler:    _ChatService_GetThread_Handler,
+		},
+		{
+			MethodName: "AckMessages",
+			Handler:    _ChatService_AckMessages_Handler,
+		},
+		{
+			MethodName: "MarkRead",
+			Handler:    _ChatService_MarkRead_Handler,
+		},
+		{
+			MethodName: "SetTyping",
+			Handler:    _ChatService_SetTyping_Handler,
+		},
+		{
+			MethodName: "SetPresence",
+			Handler:    _ChatService_SetPresence_Handler,
+		},
+		{
+			MethodName: "EditMessage",
+			Handler:    _ChatService_EditMessage_Handler,
+		},
+		{
+			MethodName: "DeleteMessage",
+			Handler:    _ChatService_DeleteMessage_Handler,
+		},
+		{
+			MethodName: "AddReaction",
+			Handler:    _ChatService_AddReaction_Handler,
+		},
+		{
+			MethodName: "RemoveReaction",
+			Handler:    _ChatService_RemoveReaction_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -352,6 +1090,22 @@ var ChatService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ChatService_SendMessage_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "UploadAttachment",
+			Handler:       _ChatService_UploadAttachment_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadAttachment",
+			Handler:       _ChatService_DownloadAttachment_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Chat",
+			Handler:       _ChatService_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "chat/v1/chat.proto",
 }
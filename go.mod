@@ -3,6 +3,8 @@ module github.com/vitthalaa/go-grpc-chat
 go 1.20
 
 require (
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/crypto v0.8.0
 	google.golang.org/grpc v1.56.2
 	google.golang.org/protobuf v1.31.0
 )
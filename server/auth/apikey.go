@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vitthalaa/go-grpc-chat/server/metadata"
+)
+
+// apiKeyHeader is the metadata key carrying a static api key
+const apiKeyHeader = "x-api-key"
+
+// APIKeyAuthenticator authenticates static api keys sent in the x-api-key metadata,
+// every key belongs to one user
+type APIKeyAuthenticator struct {
+	// keys maps the SHA-256 of a key to its user so that keys are compared in constant time
+	keys map[[sha256.Size]byte]string
+}
+
+// NewAPIKeyAuthenticator returns an APIKeyAuthenticator accepting the keys of users mapped by key
+func NewAPIKeyAuthenticator(keys map[string]string) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{
+		keys: make(map[[sha256.Size]byte]string, len(keys)),
+	}
+
+	for key, user := range keys {
+		a.keys[sha256.Sum256([]byte(key))] = user
+	}
+
+	return a
+}
+
+// LoadAPIKeys reads the api keys of path, every non-empty line not starting with # holds a key
+// followed by its user separated by whitespace
+func LoadAPIKeys(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	keys := make(map[string]string)
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a key and a user", path, line)
+		}
+
+		keys[fields[0]] = fields[1]
+	}
+
+	return keys, scanner.Err()
+}
+
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context) (Principal, error) {
+	key := metadata.Get(ctx, apiKeyHeader)
+	if key == "" {
+		return Principal{}, status.Error(codes.Unauthenticated, "no api key")
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	for known, user := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], known[:]) == 1 {
+			return Principal{Name: user, Method: "api-key"}, nil
+		}
+	}
+
+	return Principal{}, status.Error(codes.Unauthenticated, "invalid api key")
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Name is the user name of the caller
+	Name string
+	// Method names the Authenticator which resolved the caller
+	Method string
+}
+
+// Authenticator resolves the Principal of an incoming request.
+// Failures are returned as grpc status errors which are passed on to the client.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (Principal, error)
+}
+
+type principalKey struct{}
+
+// NewContext returns ctx carrying the authenticated principal p
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx by NewContext
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+
+	return p, ok && p.Name != ""
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vitthalaa/go-grpc-chat/server/metadata"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// TokenAuthenticator authenticates the session tokens issued by Login sent as bearer authorization.
+// Tokens of deleted accounts and tokens of an older generation than the account are rejected.
+type TokenAuthenticator struct {
+	tokens   *Tokens
+	accounts store.AccountStore
+}
+
+func NewTokenAuthenticator(tokens *Tokens, accounts store.AccountStore) *TokenAuthenticator {
+	return &TokenAuthenticator{
+		tokens:   tokens,
+		accounts: accounts,
+	}
+}
+
+func (a *TokenAuthenticator) Authenticate(ctx context.Context) (Principal, error) {
+	token, err := metadata.GetBearerToken(ctx)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	claims, err := a.tokens.Verify(token)
+	if err != nil {
+		return Principal{}, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	account, err := a.accounts.Account(ctx, claims.Subject)
+	if errors.Is(err, store.ErrNotFound) {
+		return Principal{}, status.Error(codes.Unauthenticated, "account does not exist")
+	}
+
+	if err != nil {
+		log.Printf("failed to get account: %v", err)
+		return Principal{}, status.Error(codes.Internal, "failed to authenticate")
+	}
+
+	if claims.Generation != account.TokenGeneration {
+		return Principal{}, status.Error(codes.Unauthenticated, "token was revoked")
+	}
+
+	return Principal{Name: claims.Subject, Method: "token"}, nil
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// CertAuthenticator authenticates the caller by the common name of its verified TLS client certificate,
+// it requires the server to verify client certificates
+type CertAuthenticator struct{}
+
+func NewCertAuthenticator() *CertAuthenticator {
+	return &CertAuthenticator{}
+}
+
+func (a *CertAuthenticator) Authenticate(ctx context.Context) (Principal, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Principal{}, status.Error(codes.Unauthenticated, "no peer")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return Principal{}, status.Error(codes.Unauthenticated, "connection is not TLS")
+	}
+
+	// only chains verified against the client CAs are trusted, not any presented certificate
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return Principal{}, status.Error(codes.Unauthenticated, "no verified client certificate")
+	}
+
+	name := chains[0][0].Subject.CommonName
+	if name == "" {
+		return Principal{}, status.Error(codes.Unauthenticated, "client certificate has no common name")
+	}
+
+	return Principal{Name: name, Method: "mtls"}, nil
+}
@@ -0,0 +1,508 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vitthalaa/go-grpc-chat/server/metadata"
+)
+
+const (
+	// clockSkew is tolerated between the issuer and the server when checking token times
+	clockSkew = time.Minute
+	// minKeyRefresh limits refetching the key set for tokens signed by unknown keys
+	minKeyRefresh = 10 * time.Second
+	// defaultKeyCacheTTL is used when the config sets no key cache TTL
+	defaultKeyCacheTTL = time.Hour
+	// fetchTimeout bounds the requests to the issuer
+	fetchTimeout = 10 * time.Second
+)
+
+// signatureHashes are the hashes of the supported JWS algorithms
+var signatureHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// OIDCConfig configures the validation of OpenID Connect tokens
+type OIDCConfig struct {
+	// Issuer is the issuer URL the tokens must be issued by, its discovery document names the key set
+	Issuer string
+	// Audience must be one of the audiences of the tokens
+	Audience string
+	// UsernameClaim is the claim holding the chat user name, sub when empty
+	UsernameClaim string
+	// KeyCacheTTL is how long the fetched key set is used before it is fetched again, an hour when zero
+	KeyCacheTTL time.Duration
+	// HTTPClient fetches discovery and key set, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// OIDCAuthenticator authenticates ID or access tokens of an OpenID Connect issuer sent as bearer authorization.
+// Tokens must be JWTs signed with RSA or ECDSA by a key of the issuer key set, which is discovered
+// and fetched on first use and cached.
+type OIDCAuthenticator struct {
+	config OIDCConfig
+	client *http.Client
+	// minRefresh is minKeyRefresh, a field so tests can shorten it
+	minRefresh time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetch       *keyFetch
+
+	// jwksURI is only used by the single running fetch
+	jwksURI string
+}
+
+// keyFetch is a running fetch of the key set, done is closed once it finished
+type keyFetch struct {
+	done chan struct{}
+}
+
+func NewOIDCAuthenticator(config OIDCConfig) *OIDCAuthenticator {
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "sub"
+	}
+
+	if config.KeyCacheTTL <= 0 {
+		config.KeyCacheTTL = defaultKeyCacheTTL
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &OIDCAuthenticator{
+		config:     config,
+		client:     client,
+		minRefresh: minKeyRefresh,
+	}
+}
+
+// oidcHeader is the JOSE header of a token
+type oidcHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// oidcClaims are the registered claims checked of every token
+type oidcClaims struct {
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// audience is the aud claim which is either a single string or an array
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+func (a *OIDCAuthenticator) Authenticate(ctx context.Context) (Principal, error) {
+	token, err := metadata.GetBearerToken(ctx)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	name, err := a.verify(ctx, token)
+	if err != nil {
+		log.Printf("rejected oidc token: %v", err)
+		return Principal{}, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	return Principal{Name: name, Method: "oidc"}, nil
+}
+
+// verify checks the signature and claims of token and returns its user name claim
+func (a *OIDCAuthenticator) verify(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("token is not a JWT")
+	}
+
+	var header oidcHeader
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return "", fmt.Errorf("header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("signature: %w", err)
+	}
+
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return "", err
+	}
+
+	err = verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature)
+	if err != nil {
+		return "", err
+	}
+
+	var claims oidcClaims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return "", fmt.Errorf("claims: %w", err)
+	}
+
+	err = a.checkClaims(claims)
+	if err != nil {
+		return "", err
+	}
+
+	var all map[string]interface{}
+	err = decodeSegment(parts[1], &all)
+	if err != nil {
+		return "", fmt.Errorf("claims: %w", err)
+	}
+
+	name, _ := all[a.config.UsernameClaim].(string)
+	if name == "" {
+		return "", fmt.Errorf("no %s claim", a.config.UsernameClaim)
+	}
+
+	return name, nil
+}
+
+// checkClaims checks issuer, audience and validity period of claims
+func (a *OIDCAuthenticator) checkClaims(claims oidcClaims) error {
+	if strings.TrimSuffix(claims.Issuer, "/") != a.config.Issuer {
+		return fmt.Errorf("issuer %q is not trusted", claims.Issuer)
+	}
+
+	found := false
+	for _, aud := range claims.Audience {
+		if aud == a.config.Audience {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("audience %v does not include %q", []string(claims.Audience), a.config.Audience)
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token expired")
+	}
+
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return errors.New("token not valid yet")
+	}
+
+	return nil
+}
+
+// key returns the public key kid of the issuer key set. The key set is fetched again
+// when the cache expired or when kid is unknown, which happens after the issuer rotated its keys.
+// Callers share a single fetch which runs without holding mu, failed fetches are retried
+// at most every minRefresh and keep the cached keys in use.
+func (a *OIDCAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	key, ok := a.keys[kid]
+	if ok && time.Since(a.fetchedAt) < a.config.KeyCacheTTL {
+		a.mu.Unlock()
+		return key, nil
+	}
+
+	fetch := a.fetch
+	if fetch == nil && a.shouldFetch(ok) {
+		fetch = &keyFetch{done: make(chan struct{})}
+		a.fetch = fetch
+
+		go a.runFetch(fetch)
+	}
+	a.mu.Unlock()
+
+	if fetch != nil {
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		a.mu.Lock()
+		key, ok = a.keys[kid]
+		a.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+// shouldFetch reports whether the key set is fetched for a token of a known or unknown key,
+// it must be called with mu held
+func (a *OIDCAuthenticator) shouldFetch(known bool) bool {
+	sinceAttempt := time.Since(a.attemptedAt)
+
+	if a.keys == nil || time.Since(a.fetchedAt) >= a.config.KeyCacheTTL {
+		retry := a.minRefresh
+		if a.config.KeyCacheTTL < retry {
+			retry = a.config.KeyCacheTTL
+		}
+
+		return sinceAttempt >= retry
+	}
+
+	return !known && sinceAttempt >= a.minRefresh
+}
+
+// runFetch fetches the key set for fetch and replaces the cached keys on success
+func (a *OIDCAuthenticator) runFetch(fetch *keyFetch) {
+	// the fetch is shared, so it is not bound to the context of the caller starting it
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	keys, err := a.fetchKeys(ctx)
+
+	a.mu.Lock()
+	a.attemptedAt = time.Now()
+	if err != nil {
+		// an unreachable issuer does not invalidate the cached keys
+		log.Printf("failed to fetch oidc keys: %v", err)
+	} else {
+		a.keys = keys
+		a.fetchedAt = time.Now()
+	}
+	a.fetch = nil
+	a.mu.Unlock()
+
+	close(fetch.done)
+}
+
+// fetchKeys discovers the key set URI of the issuer once and fetches the key set
+func (a *OIDCAuthenticator) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if a.jwksURI == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+
+		err := a.getJSON(ctx, a.config.Issuer+"/.well-known/openid-configuration", &discovery)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.TrimSuffix(discovery.Issuer, "/") != a.config.Issuer {
+			return nil, fmt.Errorf("discovery document of issuer %q", discovery.Issuer)
+		}
+
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("discovery document has no jwks_uri")
+		}
+
+		a.jwksURI = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	err := a.getJSON(ctx, a.jwksURI, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("skipping oidc key %q: %v", jwk.Kid, err)
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (a *OIDCAuthenticator) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// jsonWebKey is a public key of a JWKS
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(),
+			"P-384": elliptic.P384(),
+			"P-521": elliptic.P521(),
+		}
+
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// verifySignature checks signature of signed with key by the JWS algorithm alg,
+// alg must match the type of key so that a token can not pick a weaker verification
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	hashID, ok := signatureHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	h := hashID.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") {
+			return rsa.VerifyPKCS1v15(k, hashID, digest, signature)
+		}
+
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(k, hashID, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case *ecdsa.PublicKey:
+		if strings.HasPrefix(alg, "ES") {
+			// JWS signatures of ECDSA are r and s as fixed size big endian integers
+			size := (k.Curve.Params().BitSize + 7) / 8
+			if len(signature) != 2*size {
+				return errors.New("invalid signature length")
+			}
+
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if !ecdsa.Verify(k, digest, r, s) {
+				return errors.New("invalid signature")
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("algorithm %q does not match the key", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,410 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testIssuer is an OpenID Connect issuer serving discovery and a key set
+type testIssuer struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]crypto.Signer
+	jwksHits int
+	// down answers key set requests with an error after delay
+	down  bool
+	delay time.Duration
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	issuer := &testIssuer{keys: map[string]crypto.Signer{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+
+		issuer.jwksHits++
+
+		if issuer.down {
+			time.Sleep(issuer.delay)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		keys := []map[string]string{}
+		for kid, key := range issuer.keys {
+			keys = append(keys, publicJWK(kid, key.Public()))
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+// setKeys replaces the key set of the issuer
+func (i *testIssuer) setKeys(keys map[string]crypto.Signer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.keys = keys
+}
+
+// fail makes the key set requests fail after delay
+func (i *testIssuer) fail(delay time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.down = true
+	i.delay = delay
+}
+
+func (i *testIssuer) hits() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.jwksHits
+}
+
+func (i *testIssuer) authenticator(ttl time.Duration) *OIDCAuthenticator {
+	return NewOIDCAuthenticator(OIDCConfig{
+		Issuer:        i.server.URL,
+		Audience:      "chat",
+		UsernameClaim: "preferred_username",
+		KeyCacheTTL:   ttl,
+	})
+}
+
+// claims returns valid claims of the issuer for alice
+func (i *testIssuer) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                i.server.URL,
+		"aud":                "chat",
+		"sub":                "1234",
+		"preferred_username": "alice",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func publicJWK(kid string, key crypto.PublicKey) map[string]string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return map[string]string{
+			"kty": "EC",
+			"kid": kid,
+			"crv": k.Curve.Params().Name,
+			"x":   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			"y":   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}
+	default:
+		panic("unsupported key")
+	}
+}
+
+// sign returns a JWT of claims signed by key with the JWS algorithm alg
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(alg, "PS") {
+			signature, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestOIDCAuthenticatorAcceptsValidTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	rsaKey, ecKey := newRSAKey(t), newECKey(t)
+	issuer.setKeys(map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey})
+
+	a := issuer.authenticator(time.Hour)
+
+	for _, token := range []string{
+		sign(t, "RS256", "rsa", rsaKey, issuer.claims()),
+		sign(t, "PS256", "rsa", rsaKey, issuer.claims()),
+		sign(t, "ES256", "ec", ecKey, issuer.claims()),
+	} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+		principal, err := a.Authenticate(ctx)
+		if err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+
+		if principal.Name != "alice" || principal.Method != "oidc" {
+			t.Fatalf("principal = %+v, want alice by oidc", principal)
+		}
+	}
+}
+
+func TestOIDCAuthenticatorRejectsInvalidTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	rsaKey, ecKey := newRSAKey(t), newECKey(t)
+	issuer.setKeys(map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey})
+
+	a := issuer.authenticator(time.Hour)
+
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := issuer.claims()
+		claims[name] = value
+		return claims
+	}
+
+	parts := strings.Split(sign(t, "RS256", "rsa", rsaKey, issuer.claims()), ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"preferred_username":"bob"}`)) + "." + parts[2]
+
+	// hs256 is signed with the public key of the issuer as shared secret
+	hsHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","kid":"rsa"}`))
+	mac := hmac.New(sha256.New, rsaKey.PublicKey.N.Bytes())
+	mac.Write([]byte(hsHeader + "." + parts[1]))
+	hs256 := hsHeader + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	for name, token := range map[string]string{
+		"wrong issuer":     sign(t, "RS256", "rsa", rsaKey, with("iss", "https://evil.example.com")),
+		"wrong audience":   sign(t, "RS256", "rsa", rsaKey, with("aud", []string{"other"})),
+		"expired":          sign(t, "RS256", "rsa", rsaKey, with("exp", time.Now().Add(-time.Hour).Unix())),
+		"not valid yet":    sign(t, "RS256", "rsa", rsaKey, with("nbf", time.Now().Add(time.Hour).Unix())),
+		"no username":      sign(t, "RS256", "rsa", rsaKey, with("preferred_username", "")),
+		"foreign key":      sign(t, "RS256", "rsa", newRSAKey(t), issuer.claims()),
+		"rsa alg, ec key":  sign(t, "RS256", "ec", rsaKey, issuer.claims()),
+		"ec alg, rsa key":  sign(t, "ES256", "rsa", ecKey, issuer.claims()),
+		"hmac alg":         hs256,
+		"unknown key id":   sign(t, "RS256", "other", rsaKey, issuer.claims()),
+		"not a jwt":        "not a jwt",
+		"tampered payload": tampered,
+	} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+		_, err := a.Authenticate(ctx)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: Authenticate = %v, want Unauthenticated", name, err)
+		}
+	}
+}
+
+func TestOIDCAuthenticatorRefetchesKeysOfUnknownKid(t *testing.T) {
+	issuer := newTestIssuer(t)
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	issuer.setKeys(map[string]crypto.Signer{"old": oldKey})
+
+	a := issuer.authenticator(time.Hour)
+
+	_, err := a.verify(context.Background(), sign(t, "RS256", "old", oldKey, issuer.claims()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// the issuer rotates its keys, within minRefresh the unknown kid does not fetch the key set
+	issuer.setKeys(map[string]crypto.Signer{"new": newKey})
+	token := sign(t, "RS256", "new", newKey, issuer.claims())
+
+	_, err = a.verify(context.Background(), token)
+	if err == nil {
+		t.Fatal("verify accepted a token of an unknown key within minRefresh")
+	}
+
+	if issuer.hits() != 1 {
+		t.Fatalf("key set fetched %d times within minRefresh, want 1", issuer.hits())
+	}
+
+	a.minRefresh = 0
+
+	_, err = a.verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("verify after key rotation: %v", err)
+	}
+
+	if issuer.hits() != 2 {
+		t.Fatalf("key set fetched %d times, want 2", issuer.hits())
+	}
+}
+
+func TestOIDCAuthenticatorRefetchesExpiredKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	key := newECKey(t)
+	issuer.setKeys(map[string]crypto.Signer{"ec": key})
+
+	a := issuer.authenticator(50 * time.Millisecond)
+	token := sign(t, "ES256", "ec", key, issuer.claims())
+
+	for i := 0; i < 2; i++ {
+		_, err := a.verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+	}
+
+	if issuer.hits() != 1 {
+		t.Fatalf("key set fetched %d times within the TTL, want 1", issuer.hits())
+	}
+
+	// the TTL is shorter than minRefresh and still fetches the key set again
+	time.Sleep(60 * time.Millisecond)
+
+	_, err := a.verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	if issuer.hits() != 2 {
+		t.Fatalf("key set fetched %d times after the TTL, want 2", issuer.hits())
+	}
+
+	// keys removed from the key set are no longer accepted after the TTL
+	issuer.setKeys(map[string]crypto.Signer{})
+	time.Sleep(60 * time.Millisecond)
+
+	_, err = a.verify(context.Background(), token)
+	if err == nil {
+		t.Fatal("verify accepted a token of a removed key after the TTL")
+	}
+}
+
+func TestOIDCAuthenticatorKeepsKeysWhileIssuerIsDown(t *testing.T) {
+	issuer := newTestIssuer(t)
+	key := newECKey(t)
+	issuer.setKeys(map[string]crypto.Signer{"ec": key})
+
+	a := issuer.authenticator(50 * time.Millisecond)
+	a.minRefresh = time.Hour
+	token := sign(t, "ES256", "ec", key, issuer.claims())
+
+	_, err := a.verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	const delay = 200 * time.Millisecond
+	issuer.fail(delay)
+	time.Sleep(60 * time.Millisecond)
+
+	// concurrent callers share the failing fetch instead of queueing behind each other
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := a.verify(context.Background(), token)
+			if err != nil {
+				t.Errorf("verify with cached key: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed > 2*delay {
+		t.Fatalf("concurrent verifies took %v, want a single fetch of %v", elapsed, delay)
+	}
+
+	if issuer.hits() != 2 {
+		t.Fatalf("key set fetched %d times, want 2", issuer.hits())
+	}
+
+	// the failed fetch is not retried right away
+	start = time.Now()
+
+	_, err = a.verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed >= delay || issuer.hits() != 2 {
+		t.Fatalf("verify after a failed fetch took %v with %d fetches, want the cached key without fetching", elapsed, issuer.hits())
+	}
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against when there is no account so that
+// unknown users take as long to reject as wrong passwords
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash of password
+func HashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// CheckPassword reports whether password matches hash, a nil hash never matches
+func CheckPassword(hash []byte, password string) bool {
+	if hash == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
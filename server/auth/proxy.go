@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/vitthalaa/go-grpc-chat/server/metadata"
+)
+
+// ProxyHeaderAuthenticator trusts the user name set in a metadata header by an authenticating proxy.
+// The header is only accepted from connections of the trusted proxy networks.
+type ProxyHeaderAuthenticator struct {
+	header  string
+	proxies []*net.IPNet
+}
+
+// NewProxyHeaderAuthenticator returns a ProxyHeaderAuthenticator reading the user from header
+// of connections coming from proxies
+func NewProxyHeaderAuthenticator(header string, proxies []*net.IPNet) *ProxyHeaderAuthenticator {
+	return &ProxyHeaderAuthenticator{
+		header:  header,
+		proxies: proxies,
+	}
+}
+
+func (a *ProxyHeaderAuthenticator) Authenticate(ctx context.Context) (Principal, error) {
+	if !a.fromProxy(ctx) {
+		return Principal{}, status.Error(codes.Unauthenticated, "connection is not from a trusted proxy")
+	}
+
+	user := metadata.Get(ctx, a.header)
+	if user == "" {
+		return Principal{}, status.Errorf(codes.Unauthenticated, "no %s header", a.header)
+	}
+
+	return Principal{Name: user, Method: "proxy-header"}, nil
+}
+
+// fromProxy tells if the peer of ctx is in one of the trusted proxy networks
+func (a *ProxyHeaderAuthenticator) fromProxy(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	addr, ok := p.Addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	for _, proxy := range a.proxies {
+		if proxy.Contains(addr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
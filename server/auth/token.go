@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for malformed, tampered or expired tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// tokenHeader is the fixed JWT header of HS256 tokens
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims are the verified contents of a session token.
+// Generation allows revoking all tokens of a subject by issuing new ones for a higher generation.
+type Claims struct {
+	Subject    string
+	Generation int64
+	IssuedAt   time.Time
+	ExpiresAt  time.Time
+}
+
+// claims is the JSON payload of a token
+type claims struct {
+	Subject    string `json:"sub"`
+	Generation int64  `json:"gen"`
+	IssuedAt   int64  `json:"iat"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// Tokens issues and verifies session tokens which are JWTs signed with HMAC-SHA256
+type Tokens struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewTokens returns Tokens signing with key, issued tokens expire after ttl
+func NewTokens(key []byte, ttl time.Duration) *Tokens {
+	return &Tokens{
+		key: key,
+		ttl: ttl,
+	}
+}
+
+// Issue returns a token of generation for user and its expiry time
+func (t *Tokens) Issue(user string, generation int64) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+
+	payload, err := json.Marshal(claims{
+		Subject:    user,
+		Generation: generation,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return unsigned + "." + t.sign(unsigned), expiresAt, nil
+}
+
+// Verify checks the signature and expiry of token and returns its claims
+func (t *Tokens) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return Claims{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	expected, _ := base64.RawURLEncoding.DecodeString(t.sign(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, expected) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var c claims
+	err = json.Unmarshal(payload, &c)
+	if err != nil || c.Subject == "" {
+		return Claims{}, ErrInvalidToken
+	}
+
+	res := Claims{
+		Subject:    c.Subject,
+		Generation: c.Generation,
+		IssuedAt:   time.Unix(c.IssuedAt, 0),
+		ExpiresAt:  time.Unix(c.ExpiresAt, 0),
+	}
+
+	if !time.Now().Before(res.ExpiresAt) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	return res, nil
+}
+
+func (t *Tokens) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(unsigned))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
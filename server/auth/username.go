@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"fmt"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+)
+
+// ValidateUsername checks username is 3 to 32 ASCII letters, digits, '.', '_' or '-'.
+// Names must not contain '/' which separates the parts of storage keys.
+func ValidateUsername(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return fmt.Errorf("user name must be %d to %d characters", minUsernameLength, maxUsernameLength)
+	}
+
+	for _, r := range username {
+		ok := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-'
+		if !ok {
+			return fmt.Errorf("user name must only contain letters, digits, '.', '_' or '-'")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("not found")
+
+// Store keeps the content of attachments by id
+type Store interface {
+	// Put stores the content read from r under id, a failed Put leaves nothing behind
+	Put(ctx context.Context, id string, r io.Reader) error
+	// Get opens the content stored under id or returns ErrNotFound
+	Get(ctx context.Context, id string) (io.ReadCloser, error)
+	// Delete removes the content stored under id
+	Delete(ctx context.Context, id string) error
+}
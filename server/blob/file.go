@@ -0,0 +1,94 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore is a Store keeping every blob in a file of a local directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore in dir, dir is created when missing
+func NewFileStore(dir string) (*FileStore, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		dir: dir,
+	}, nil
+}
+
+func (s *FileStore) Put(_ context.Context, id string, r io.Reader) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	// content is written to a temporary file first so that readers never see partial blobs
+	f, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) Get(_ context.Context, id string) (io.ReadCloser, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// path returns the file of blob id, ids must not leave the directory
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.HasPrefix(id, ".") || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("invalid blob id %q", id)
+	}
+
+	return filepath.Join(s.dir, id), nil
+}
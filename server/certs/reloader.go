@@ -0,0 +1,144 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var errNoCertificates = errors.New("no PEM certificates found")
+
+// Reloader provides the TLS certificate of a server and the CAs of client certificates
+// loaded from files. Files are checked for changes on every handshake, changed files are
+// loaded again so that renewed certificates are used without restart. When a changed file
+// can not be loaded the previous certificates are kept.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// NewReloader loads the key pair of certFile and keyFile and the client CAs of caFile,
+// client certificates are not requested when caFile is empty
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		modTimes: make(map[string]time.Time),
+	}
+
+	_, err := r.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ServerConfig returns a TLS config of the server using the current certificates of every handshake
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCA := r.current()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+
+			if clientCA != nil {
+				config.ClientCAs = clientCA
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+
+			return config, nil
+		},
+	}
+}
+
+// current returns the certificates after loading changed files
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reloaded, err := r.reload()
+	if err != nil {
+		log.Printf("failed to reload certificates, keeping the previous ones: %v", err)
+	} else if reloaded {
+		log.Println("Reloaded certificates")
+	}
+
+	return r.cert, r.clientCA
+}
+
+// reload loads the files when any of them changed since the last load, it must be called with mu held
+// or before r is shared
+func (r *Reloader) reload() (bool, error) {
+	modTimes := make(map[string]time.Time)
+	changed := false
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+
+		modTimes[file] = info.ModTime()
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	var clientCA *x509.CertPool
+	if r.caFile != "" {
+		clientCA, err = LoadCertPool(r.caFile)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	r.cert = &cert
+	r.clientCA = clientCA
+	r.modTimes = modTimes
+
+	return true, nil
+}
+
+// LoadCertPool returns a pool of the PEM encoded certificates of file
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: %w", file, errNoCertificates)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,195 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/vitthalaa/go-grpc-chat/server/certs/certstest"
+)
+
+// serve accepts TLS connections of r until the test ends, the handshake error of every connection
+// is sent on the returned channel
+func serve(t *testing.T, r *Reloader) (string, <-chan error) {
+	t.Helper()
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", r.ServerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { lis.Close() })
+
+	handshakes := make(chan error, 16)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			err = conn.(*tls.Conn).Handshake()
+			handshakes <- err
+			if err == nil {
+				_, _ = conn.Write([]byte("ok"))
+			}
+
+			conn.Close()
+		}
+	}()
+
+	return lis.Addr().String(), handshakes
+}
+
+// dial connects to addr and returns the common name of the server certificate
+func dial(addr string, config *tls.Config) (string, error) {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return "", err
+	}
+
+	defer conn.Close()
+
+	// the server rejects a missing client certificate after the client finished its handshake
+	_, err = io.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func clientConfig(t *testing.T, ca *certstest.CA, certificates ...tls.Certificate) *tls.Config {
+	t.Helper()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.PEM)
+
+	return &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: certificates,
+	}
+}
+
+func TestReloaderServesTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t)
+
+	cert, key := ca.Issue(t, "server", x509.ExtKeyUsageServerAuth)
+	certstest.WriteFile(t, filepath.Join(dir, "server.crt"), cert)
+	certstest.WriteFile(t, filepath.Join(dir, "server.key"), key)
+
+	r, err := NewReloader(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), "")
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	addr, _ := serve(t, r)
+
+	name, err := dial(addr, clientConfig(t, ca))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	if name != "server" {
+		t.Fatalf("server certificate of %q, want server", name)
+	}
+}
+
+func TestReloaderRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t)
+
+	cert, key := ca.Issue(t, "server", x509.ExtKeyUsageServerAuth)
+	certstest.WriteFile(t, filepath.Join(dir, "server.crt"), cert)
+	certstest.WriteFile(t, filepath.Join(dir, "server.key"), key)
+	certstest.WriteFile(t, filepath.Join(dir, "ca.crt"), ca.PEM)
+
+	r, err := NewReloader(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	addr, handshakes := serve(t, r)
+
+	_, err = dial(addr, clientConfig(t, ca))
+	if err == nil {
+		t.Fatal("dial without client certificate succeeded")
+	}
+
+	if err := <-handshakes; err == nil {
+		t.Fatal("server accepted a handshake without client certificate")
+	}
+
+	clientCert, clientKey := ca.Issue(t, "alice", x509.ExtKeyUsageClientAuth)
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dial(addr, clientConfig(t, ca, pair))
+	if err != nil {
+		t.Fatalf("dial with client certificate: %v", err)
+	}
+
+	if err := <-handshakes; err != nil {
+		t.Fatalf("server rejected the client certificate: %v", err)
+	}
+
+	other := certstest.NewCA(t)
+	otherCert, otherKey := other.Issue(t, "mallory", x509.ExtKeyUsageClientAuth)
+	otherPair, err := tls.X509KeyPair(otherCert, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = dial(addr, clientConfig(t, ca, otherPair))
+	if err := <-handshakes; err == nil {
+		t.Fatal("server accepted a client certificate of an unknown CA")
+	}
+}
+
+func TestReloaderReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t)
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+
+	cert, key := ca.Issue(t, "first", x509.ExtKeyUsageServerAuth)
+	certstest.WriteFile(t, certFile, cert)
+	certstest.WriteFile(t, keyFile, key)
+
+	r, err := NewReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	addr, _ := serve(t, r)
+
+	cert, key = ca.Issue(t, "second", x509.ExtKeyUsageServerAuth)
+	certstest.WriteFile(t, certFile, cert)
+	certstest.WriteFile(t, keyFile, key)
+
+	name, err := dial(addr, clientConfig(t, ca))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	if name != "second" {
+		t.Fatalf("server certificate of %q after rewriting the files, want second", name)
+	}
+
+	// a broken file keeps the previous certificate in use
+	certstest.WriteFile(t, certFile, []byte("not a certificate"))
+
+	name, err = dial(addr, clientConfig(t, ca))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	if name != "second" {
+		t.Fatalf("server certificate of %q after breaking the files, want second", name)
+	}
+}
@@ -0,0 +1,521 @@
+package hub
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+// Channel is a chat channel of either a user or a group.
+// Admins are the members allowed to moderate a group, the creator of a group is its first admin.
+type Channel struct {
+	Type   pb.ChannelType
+	Name   string
+	Users  []string
+	Admins []string
+}
+
+// HasUser reports whether user is a member of the channel
+func (c Channel) HasUser(user string) bool {
+	for _, u := range c.Users {
+		if u == user {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAdmin reports whether user is an admin of the channel
+func (c Channel) IsAdmin(user string) bool {
+	for _, u := range c.Admins {
+		if u == user {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Channel) clone() Channel {
+	return Channel{
+		Type:   c.Type,
+		Name:   c.Name,
+		Users:  append([]string(nil), c.Users...),
+		Admins: append([]string(nil), c.Admins...),
+	}
+}
+
+const defaultQueueSize = 64
+
+// Config configures the outbound queues of sessions
+type Config struct {
+	// QueueSize is the number of events buffered per session
+	QueueSize int
+	// Overflow is applied when a session queue is full
+	Overflow OverflowPolicy
+	// PendingQueueSize is the number of unacknowledged messages kept per user
+	PendingQueueSize int
+	// PendingTTL is how long unacknowledged messages are kept for redelivery
+	PendingTTL time.Duration
+}
+
+// Hub is a concurrency safe registry owning users, groups and group membership.
+// All state is guarded by a single RWMutex, values handed out are copies.
+// A user stays known after disconnecting, only its session is removed.
+type Hub struct {
+	cfg Config
+
+	mu       sync.RWMutex
+	channels map[string]*Channel
+	sessions map[string]*Session
+	pending  map[string]*pendingQueue
+	presence map[string]*userPresence
+	contacts map[string]map[string]bool
+	dropped  map[string]uint64
+}
+
+func New(cfg Config) *Hub {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+
+	if cfg.PendingQueueSize <= 0 {
+		cfg.PendingQueueSize = defaultPendingQueueSize
+	}
+
+	if cfg.PendingTTL <= 0 {
+		cfg.PendingTTL = defaultPendingTTL
+	}
+
+	return &Hub{
+		cfg:      cfg,
+		channels: make(map[string]*Channel),
+		sessions: make(map[string]*Session),
+		pending:  make(map[string]*pendingQueue),
+		presence: make(map[string]*userPresence),
+		contacts: make(map[string]map[string]bool),
+		dropped:  make(map[string]uint64),
+	}
+}
+
+// Connect registers user and opens a new session its events are delivered to.
+// A previous session of user is replaced and closed with Aborted, so that a client
+// can reconnect before the server noticed that its old connection is gone.
+// Messages not acknowledged by the user yet are handed over as the session backlog.
+func (h *Hub) Connect(user string) (*Session, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c, ok := h.channels[user]; ok && c.Type != pb.ChannelType_USER {
+		return nil, status.Errorf(codes.AlreadyExists, "name %s is taken by a group", user)
+	}
+
+	if old, ok := h.sessions[user]; ok {
+		old.closeWithError(status.Errorf(codes.Aborted, "user %s connected again", user))
+	}
+
+	if _, ok := h.channels[user]; !ok {
+		h.channels[user] = &Channel{
+			Type: pb.ChannelType_USER,
+			Name: user,
+		}
+	}
+
+	session := newSession(user, h.cfg.QueueSize)
+	h.sessions[user] = session
+	h.setOnline(user)
+
+	if queue, ok := h.pending[user]; ok {
+		session.backlog = queue.events()
+	}
+
+	return session, nil
+}
+
+// AddUser registers user without connecting it, so that messages can be queued for it
+// before its first session. Users and groups share one namespace, so adding the user
+// reserves its name against groups. It reports whether user was not known before.
+func (h *Hub) AddUser(user string) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.channels[user]
+	if ok && c.Type != pb.ChannelType_USER {
+		return false, status.Errorf(codes.AlreadyExists, "name %s is taken by a group", user)
+	}
+
+	if ok {
+		return false, nil
+	}
+
+	h.channels[user] = &Channel{
+		Type: pb.ChannelType_USER,
+		Name: user,
+	}
+
+	return true, nil
+}
+
+// RemoveUser undoes AddUser of a user which never connected and has no messages queued
+func (h *Hub) RemoveUser(user string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.channels[user]
+	if !ok || c.Type != pb.ChannelType_USER {
+		return
+	}
+
+	if _, ok := h.sessions[user]; ok {
+		return
+	}
+
+	if _, ok := h.pending[user]; ok {
+		return
+	}
+
+	delete(h.channels, user)
+}
+
+// Disconnect tears down session and marks its user offline.
+// Deliveries blocked on the session are released.
+// It reports whether session was the current session of its user, a replaced session
+// leaves the user online.
+func (h *Hub) Disconnect(session *Session) bool {
+	h.mu.Lock()
+	current := h.sessions[session.User] == session
+	if current {
+		delete(h.sessions, session.User)
+		h.setOffline(session.User)
+	}
+	h.mu.Unlock()
+
+	session.close()
+
+	return current
+}
+
+// IsConnected reports whether user has a live session
+func (h *Hub) IsConnected(user string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	_, ok := h.sessions[user]
+
+	return ok
+}
+
+// Channel returns a copy of the channel registered under name
+func (h *Hub) Channel(name string) (Channel, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	c, ok := h.channels[name]
+	if !ok {
+		return Channel{}, false
+	}
+
+	return c.clone(), true
+}
+
+// Channels returns copies of all registered channels
+func (h *Hub) Channels() []Channel {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	channels := make([]Channel, 0, len(h.channels))
+	for _, c := range h.channels {
+		channels = append(channels, c.clone())
+	}
+
+	return channels
+}
+
+// CreateGroup registers a new group with user as its first member
+func (h *Hub) CreateGroup(name, user string) error {
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "channel name is required")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.channels[name]; ok {
+		return status.Errorf(codes.AlreadyExists, "channel %s already exists", name)
+	}
+
+	h.channels[name] = &Channel{
+		Type:   pb.ChannelType_GROUP,
+		Name:   name,
+		Users:  []string{user},
+		Admins: []string{user},
+	}
+
+	return nil
+}
+
+// RestoreGroup registers a group with its members and admins as persisted before a restart
+func (h *Hub) RestoreGroup(name string, users, admins []string) error {
+	if len(users) == 0 {
+		return status.Errorf(codes.InvalidArgument, "group %s has no members", name)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.channels[name]; ok {
+		return status.Errorf(codes.AlreadyExists, "channel %s already exists", name)
+	}
+
+	channel := &Channel{
+		Type:   pb.ChannelType_GROUP,
+		Name:   name,
+		Users:  append([]string(nil), users...),
+		Admins: append([]string(nil), admins...),
+	}
+
+	if len(channel.Admins) == 0 {
+		channel.Admins = []string{users[0]}
+	}
+
+	h.channels[name] = channel
+
+	return nil
+}
+
+// JoinGroup adds user to the members of group name
+func (h *Hub) JoinGroup(name, user string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	channel, err := h.getGroup(name)
+	if err != nil {
+		return err
+	}
+
+	if channel.HasUser(user) {
+		return status.Errorf(codes.AlreadyExists, "already a member of %s", name)
+	}
+
+	channel.Users = append(channel.Users, user)
+
+	return nil
+}
+
+// LeaveGroup removes user from the members of group name.
+// The group is deleted once its last member leaves, when its last admin
+// leaves the longest standing member becomes admin.
+func (h *Hub) LeaveGroup(name, user string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	channel, err := h.getGroup(name)
+	if err != nil {
+		return err
+	}
+
+	if !channel.HasUser(user) {
+		return status.Errorf(codes.FailedPrecondition, "not a member of %s", name)
+	}
+
+	h.leaveGroup(channel, user)
+
+	return nil
+}
+
+// DeleteUser forgets user with its channel, pending messages and presence, removes it from
+// all its groups and closes its live session with err.
+// It returns the groups user left which still exist afterwards.
+func (h *Hub) DeleteUser(user string, err error) []string {
+	h.mu.Lock()
+
+	var groups []string
+	for name, channel := range h.channels {
+		if channel.Type == pb.ChannelType_GROUP && channel.HasUser(user) && h.leaveGroup(channel, user) {
+			groups = append(groups, name)
+		}
+	}
+
+	if channel, ok := h.channels[user]; ok && channel.Type == pb.ChannelType_USER {
+		delete(h.channels, user)
+	}
+
+	session := h.sessions[user]
+	delete(h.sessions, user)
+	delete(h.pending, user)
+	delete(h.presence, user)
+	delete(h.contacts, user)
+	delete(h.dropped, user)
+
+	for _, contacts := range h.contacts {
+		delete(contacts, user)
+	}
+
+	h.mu.Unlock()
+
+	if session != nil {
+		session.closeWithError(err)
+	}
+
+	return groups
+}
+
+// leaveGroup removes member user from channel and reports whether the group still exists.
+// It must be called with mu held.
+func (h *Hub) leaveGroup(channel *Channel, user string) bool {
+	users := removeUser(channel.Users, user)
+	if len(users) == 0 {
+		delete(h.channels, channel.Name)
+		return false
+	}
+
+	channel.Users = users
+	channel.Admins = removeUser(channel.Admins, user)
+	if len(channel.Admins) == 0 {
+		channel.Admins = []string{users[0]}
+	}
+
+	return true
+}
+
+// Deliver queues event on the session of user without blocking.
+// A full queue is handled by the configured overflow policy.
+func (h *Hub) Deliver(user string, event *pb.ServerEvent) error {
+	h.mu.RLock()
+	session, ok := h.sessions[user]
+	h.mu.RUnlock()
+
+	if !ok {
+		return status.Errorf(codes.Unavailable, "user %s is offline", user)
+	}
+
+	dropped, err := session.enqueue(event, h.cfg.Overflow)
+	if dropped > 0 {
+		log.Printf("dropped %d event(s) for slow consumer %s", dropped, user)
+
+		h.mu.Lock()
+		h.dropped[user] += uint64(dropped)
+		h.mu.Unlock()
+	}
+
+	if err != nil && session.Err() != nil {
+		// slow consumer was disconnected by the overflow policy
+		h.Disconnect(session)
+	}
+
+	return err
+}
+
+// DeliverMessage delivers the message event to user like Deliver and keeps it
+// until user acknowledges it, so that it is redelivered on the next session
+// when user is offline or does not ack it in time
+func (h *Hub) DeliverMessage(user string, event *pb.ServerEvent) error {
+	h.mu.Lock()
+
+	if c, ok := h.channels[user]; !ok || c.Type != pb.ChannelType_USER {
+		h.mu.Unlock()
+		return status.Errorf(codes.NotFound, "invalid receiver %s", user)
+	}
+
+	queue, ok := h.pending[user]
+	if !ok {
+		queue = &pendingQueue{}
+		h.pending[user] = queue
+	}
+
+	dropped := queue.push(event, h.cfg.PendingQueueSize, h.cfg.PendingTTL)
+	if dropped > 0 {
+		log.Printf("dropped %d unacknowledged message(s) of %s", dropped, user)
+		h.dropped[user] += uint64(dropped)
+	}
+
+	// live delivery happens under the hub lock, so that a concurrent Connect
+	// gets the message either in its backlog or in its queue but not in both
+	session, ok := h.sessions[user]
+	if !ok {
+		h.mu.Unlock()
+		return nil
+	}
+
+	dropped, err := session.enqueue(event, h.cfg.Overflow)
+	if dropped > 0 {
+		log.Printf("dropped %d event(s) for slow consumer %s", dropped, user)
+		h.dropped[user] += uint64(dropped)
+	}
+
+	h.mu.Unlock()
+
+	if err != nil && session.Err() != nil {
+		h.Disconnect(session)
+	}
+
+	return err
+}
+
+// Ack removes the messages with the given ids from the unacknowledged messages
+// of user and returns the removed messages
+func (h *Hub) Ack(user string, ids []string) []*pb.Message {
+	idSet := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		idSet[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	queue, ok := h.pending[user]
+	if !ok {
+		return nil
+	}
+
+	return queue.ack(idSet)
+}
+
+// DroppedMessages returns the number of events dropped per user
+func (h *Hub) DroppedMessages() map[string]uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	dropped := make(map[string]uint64, len(h.dropped))
+	for user, n := range h.dropped {
+		dropped[user] = n
+	}
+
+	return dropped
+}
+
+// getGroup must be called with mu held
+func (h *Hub) getGroup(name string) (*Channel, error) {
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "channel name is required")
+	}
+
+	channel, ok := h.channels[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "channel %s not found", name)
+	}
+
+	if channel.Type != pb.ChannelType_GROUP {
+		return nil, status.Errorf(codes.FailedPrecondition, "channel %s is not a group", name)
+	}
+
+	return channel, nil
+}
+
+// removeUser returns users without user
+func removeUser(users []string, user string) []string {
+	res := make([]string, 0, len(users))
+	for _, u := range users {
+		if u != user {
+			res = append(res, u)
+		}
+	}
+
+	return res
+}
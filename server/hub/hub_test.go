@@ -0,0 +1,252 @@
+package hub
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+const (
+	testClients = 300
+	testGroups  = 10
+	testRounds  = 5
+)
+
+func messageEvent(id, sender string) *pb.ServerEvent {
+	return &pb.ServerEvent{
+		Event: &pb.ServerEvent_Message{
+			Message: &pb.Message{Id: id, Sender: sender},
+		},
+	}
+}
+
+// drain consumes the events of session until it is closed
+func drain(session *Session) {
+	for {
+		select {
+		case <-session.Done():
+			return
+		case <-session.Events():
+		}
+	}
+}
+
+func TestHubConcurrentClients(t *testing.T) {
+	h := New(Config{QueueSize: 8})
+
+	for g := 0; g < testGroups; g++ {
+		err := h.CreateGroup(fmt.Sprintf("group-%d", g), "owner")
+		if err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < testClients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			user := fmt.Sprintf("user-%d", i)
+			group := fmt.Sprintf("group-%d", i%testGroups)
+
+			for round := 0; round < testRounds; round++ {
+				session, err := h.Connect(user)
+				if err != nil {
+					t.Errorf("Connect %s: %v", user, err)
+					return
+				}
+
+				go drain(session)
+
+				err = h.JoinGroup(group, user)
+				if err != nil {
+					t.Errorf("JoinGroup %s: %v", user, err)
+				}
+
+				channel, ok := h.Channel(group)
+				if !ok {
+					t.Errorf("group %s is missing", group)
+				}
+
+				for _, member := range channel.Users {
+					_ = h.Deliver(member, messageEvent("", user))
+				}
+
+				peer := fmt.Sprintf("user-%d", (i+1)%testClients)
+				_ = h.DeliverMessage(peer, messageEvent(fmt.Sprintf("%s-%d", user, round), user))
+				h.Ack(user, []string{fmt.Sprintf("user-%d-%d", (i+testClients-1)%testClients, round)})
+
+				_ = h.Channels()
+				_ = h.IsConnected(peer)
+
+				err = h.LeaveGroup(group, user)
+				if err != nil {
+					t.Errorf("LeaveGroup %s: %v", user, err)
+				}
+
+				h.Disconnect(session)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := 0; i < testClients; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		if h.IsConnected(user) {
+			t.Errorf("%s is still connected", user)
+		}
+
+		channel, ok := h.Channel(user)
+		if !ok || channel.Type != pb.ChannelType_USER {
+			t.Errorf("%s is not known as a user after disconnecting", user)
+		}
+	}
+
+	for g := 0; g < testGroups; g++ {
+		channel, ok := h.Channel(fmt.Sprintf("group-%d", g))
+		if !ok {
+			t.Fatalf("group-%d was deleted", g)
+		}
+
+		if len(channel.Users) != 1 || channel.Users[0] != "owner" {
+			t.Errorf("group-%d members = %v, want only the owner", g, channel.Users)
+		}
+	}
+}
+
+func TestHubConcurrentGroupMembership(t *testing.T) {
+	h := New(Config{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < testClients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			user := fmt.Sprintf("user-%d", i)
+
+			// whoever comes first creates the group, everybody else joins it
+			if h.CreateGroup("lobby", user) != nil {
+				err := h.JoinGroup("lobby", user)
+				if err != nil {
+					t.Errorf("JoinGroup %s: %v", user, err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	channel, ok := h.Channel("lobby")
+	if !ok {
+		t.Fatal("lobby is missing")
+	}
+
+	if len(channel.Users) != testClients {
+		t.Fatalf("lobby has %d members, want %d", len(channel.Users), testClients)
+	}
+
+	for i := 0; i < testClients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := h.LeaveGroup("lobby", fmt.Sprintf("user-%d", i))
+			if err != nil {
+				t.Errorf("LeaveGroup: %v", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if _, ok := h.Channel("lobby"); ok {
+		t.Error("lobby still exists after all members left")
+	}
+}
+
+func TestHubRedeliversUnacknowledgedMessages(t *testing.T) {
+	h := New(Config{})
+
+	session, err := h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	h.Disconnect(session)
+
+	err = h.DeliverMessage("bob", messageEvent("m1", "alice"))
+	if err != nil {
+		t.Fatalf("DeliverMessage: %v", err)
+	}
+
+	session, err = h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	defer h.Disconnect(session)
+
+	backlog := session.Backlog()
+	if len(backlog) != 1 || backlog[0].GetMessage().GetId() != "m1" {
+		t.Fatalf("backlog = %v, want m1", backlog)
+	}
+
+	acked := h.Ack("bob", []string{"m1"})
+	if len(acked) != 1 {
+		t.Fatalf("Ack removed %d messages, want 1", len(acked))
+	}
+}
+
+func TestHubConnectReplacesSession(t *testing.T) {
+	h := New(Config{})
+
+	old, err := h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	session, err := h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect while the old session is registered: %v", err)
+	}
+
+	select {
+	case <-old.Done():
+	default:
+		t.Fatal("old session is still open")
+	}
+
+	if status.Code(old.Err()) != codes.Aborted {
+		t.Fatalf("old session closed with %v, want Aborted", old.Err())
+	}
+
+	// the old connection noticing its end does not disconnect the new session
+	if h.Disconnect(old) {
+		t.Fatal("Disconnect of the replaced session reported it as current")
+	}
+
+	if !h.IsConnected("bob") {
+		t.Fatal("bob is offline after the replaced session disconnected")
+	}
+
+	err = h.Deliver("bob", messageEvent("m1", "alice"))
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	if event := <-session.Events(); event.GetMessage().GetId() != "m1" {
+		t.Fatalf("new session got %v, want m1", event)
+	}
+
+	if !h.Disconnect(session) || h.IsConnected("bob") {
+		t.Fatal("bob is still connected after disconnecting the new session")
+	}
+}
@@ -0,0 +1,87 @@
+package hub
+
+import (
+	"time"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+const (
+	defaultPendingQueueSize = 100
+	defaultPendingTTL       = 24 * time.Hour
+)
+
+// pendingMessage is a message kept for a user until it is acknowledged or expires
+type pendingMessage struct {
+	event     *pb.ServerEvent
+	expiresAt time.Time
+}
+
+// pendingQueue is the bounded list of messages a user has not acknowledged yet,
+// either because the user was offline or the client did not ack them.
+// It is not safe for concurrent use, the hub guards it with its lock.
+type pendingQueue struct {
+	messages []pendingMessage
+}
+
+// push appends event and returns how many messages were dropped to stay within size
+func (q *pendingQueue) push(event *pb.ServerEvent, size int, ttl time.Duration) int {
+	now := time.Now()
+	dropped := q.expire(now)
+
+	q.messages = append(q.messages, pendingMessage{
+		event:     event,
+		expiresAt: now.Add(ttl),
+	})
+
+	if over := len(q.messages) - size; over > 0 {
+		q.messages = q.messages[over:]
+		dropped += over
+	}
+
+	return dropped
+}
+
+// events returns the messages which have not expired yet in the order they were queued
+func (q *pendingQueue) events() []*pb.ServerEvent {
+	q.expire(time.Now())
+
+	events := make([]*pb.ServerEvent, 0, len(q.messages))
+	for _, m := range q.messages {
+		events = append(events, m.event)
+	}
+
+	return events
+}
+
+// ack removes the messages with the given ids and returns them
+func (q *pendingQueue) ack(ids map[string]bool) []*pb.Message {
+	var acked []*pb.Message
+
+	messages := q.messages[:0]
+	for _, m := range q.messages {
+		msg := m.event.GetMessage()
+		if ids[msg.GetId()] {
+			acked = append(acked, msg)
+			continue
+		}
+
+		messages = append(messages, m)
+	}
+
+	q.messages = messages
+
+	return acked
+}
+
+// expire removes messages expired at now and returns how many were removed
+func (q *pendingQueue) expire(now time.Time) int {
+	i := 0
+	for i < len(q.messages) && !now.Before(q.messages[i].expiresAt) {
+		i++
+	}
+
+	q.messages = q.messages[i:]
+
+	return i
+}
@@ -0,0 +1,66 @@
+package hub
+
+import (
+	"testing"
+	"time"
+)
+
+// backlogIDs connects user, returns the message ids of the backlog and disconnects again
+func backlogIDs(t *testing.T, h *Hub, user string) []string {
+	t.Helper()
+
+	session, err := h.Connect(user)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	defer h.Disconnect(session)
+
+	var ids []string
+	for _, event := range session.Backlog() {
+		ids = append(ids, event.GetMessage().GetId())
+	}
+
+	return ids
+}
+
+func TestPendingQueueKeepsNewestMessages(t *testing.T) {
+	h := New(Config{PendingQueueSize: 2})
+	backlogIDs(t, h, "bob")
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		err := h.DeliverMessage("bob", messageEvent(id, "alice"))
+		if err != nil {
+			t.Fatalf("DeliverMessage: %v", err)
+		}
+	}
+
+	if ids := backlogIDs(t, h, "bob"); len(ids) != 2 || ids[0] != "m2" || ids[1] != "m3" {
+		t.Fatalf("backlog = %v, want [m2 m3]", ids)
+	}
+
+	if dropped := h.DroppedMessages()["bob"]; dropped != 1 {
+		t.Fatalf("%d messages dropped, want 1", dropped)
+	}
+}
+
+func TestPendingMessagesExpire(t *testing.T) {
+	h := New(Config{PendingTTL: 20 * time.Millisecond})
+	backlogIDs(t, h, "bob")
+
+	err := h.DeliverMessage("bob", messageEvent("m1", "alice"))
+	if err != nil {
+		t.Fatalf("DeliverMessage: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	err = h.DeliverMessage("bob", messageEvent("m2", "alice"))
+	if err != nil {
+		t.Fatalf("DeliverMessage: %v", err)
+	}
+
+	if ids := backlogIDs(t, h, "bob"); len(ids) != 1 || ids[0] != "m2" {
+		t.Fatalf("backlog = %v, want only the message which did not expire", ids)
+	}
+}
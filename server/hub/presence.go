@@ -0,0 +1,138 @@
+package hub
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+// Presence is the availability of a user
+type Presence struct {
+	Status   pb.PresenceStatus
+	LastSeen time.Time
+}
+
+// userPresence is the presence of a user with the status the user chose,
+// which is restored when the user connects again
+type userPresence struct {
+	Presence
+	chosen pb.PresenceStatus
+}
+
+// Presence returns the presence of user, ok is false for unknown users
+func (h *Hub) Presence(user string) (Presence, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	p, ok := h.presence[user]
+	if !ok {
+		return Presence{}, false
+	}
+
+	return p.Presence, true
+}
+
+// SetStatus sets the status chosen by the connected user
+func (h *Hub) SetStatus(user string, s pb.PresenceStatus) error {
+	if _, ok := pb.PresenceStatus_name[int32(s)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown status %d", s)
+	}
+
+	// offline is the zero value, so it also rejects requests without a status
+	if s == pb.PresenceStatus_OFFLINE {
+		return status.Error(codes.InvalidArgument, "offline status can not be set")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.presence[user]
+	if !ok || p.Status == pb.PresenceStatus_OFFLINE {
+		return status.Errorf(codes.FailedPrecondition, "user %s is offline", user)
+	}
+
+	p.chosen = s
+	p.Status = s
+
+	return nil
+}
+
+// AddContact records that users a and b have a direct conversation
+func (h *Hub) AddContact(a, b string) {
+	if a == b {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.addContact(a, b)
+	h.addContact(b, a)
+}
+
+// Peers returns the users sharing a group or a direct conversation with user
+func (h *Hub) Peers(user string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	peers := make(map[string]bool)
+	for peer := range h.contacts[user] {
+		peers[peer] = true
+	}
+
+	for _, c := range h.channels {
+		if c.Type != pb.ChannelType_GROUP || !c.HasUser(user) {
+			continue
+		}
+
+		for _, u := range c.Users {
+			peers[u] = true
+		}
+	}
+
+	delete(peers, user)
+
+	res := make([]string, 0, len(peers))
+	for peer := range peers {
+		res = append(res, peer)
+	}
+
+	return res
+}
+
+// addContact must be called with mu held
+func (h *Hub) addContact(user, contact string) {
+	contacts, ok := h.contacts[user]
+	if !ok {
+		contacts = make(map[string]bool)
+		h.contacts[user] = contacts
+	}
+
+	contacts[contact] = true
+}
+
+// setOnline must be called with mu held
+func (h *Hub) setOnline(user string) {
+	p, ok := h.presence[user]
+	if !ok {
+		p = &userPresence{chosen: pb.PresenceStatus_ONLINE}
+		h.presence[user] = p
+	}
+
+	p.Status = p.chosen
+	p.LastSeen = time.Now()
+}
+
+// setOffline must be called with mu held
+func (h *Hub) setOffline(user string) {
+	p, ok := h.presence[user]
+	if !ok {
+		return
+	}
+
+	p.Status = pb.PresenceStatus_OFFLINE
+	p.LastSeen = time.Now()
+}
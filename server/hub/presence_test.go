@@ -0,0 +1,57 @@
+package hub
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+func TestSetStatusRejectsInvalidStatus(t *testing.T) {
+	h := New(Config{})
+
+	session, err := h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	defer h.Disconnect(session)
+
+	for _, s := range []pb.PresenceStatus{pb.PresenceStatus_OFFLINE, pb.PresenceStatus(42), pb.PresenceStatus(-1)} {
+		err = h.SetStatus("bob", s)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("SetStatus(%d): %v, want InvalidArgument", s, err)
+		}
+	}
+
+	if p, _ := h.Presence("bob"); p.Status != pb.PresenceStatus_ONLINE {
+		t.Fatalf("status = %v after invalid updates, want ONLINE", p.Status)
+	}
+
+	err = h.SetStatus("bob", pb.PresenceStatus_AWAY)
+	if err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+
+	h.Disconnect(session)
+
+	if p, _ := h.Presence("bob"); p.Status != pb.PresenceStatus_OFFLINE || p.LastSeen.IsZero() {
+		t.Fatalf("presence after disconnect = %+v, want offline with last seen", p)
+	}
+
+	session, err = h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	if p, _ := h.Presence("bob"); p.Status != pb.PresenceStatus_AWAY {
+		t.Fatalf("status after reconnect = %v, want the chosen AWAY", p.Status)
+	}
+
+	err = h.SetStatus("alice", pb.PresenceStatus_AWAY)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("SetStatus of offline alice: %v, want FailedPrecondition", err)
+	}
+}
@@ -0,0 +1,188 @@
+package hub
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+// OverflowPolicy decides what happens when the outbound queue of a session is full
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest queued event to make room for the new one
+	DropOldest OverflowPolicy = iota
+	// DisconnectSlow closes the session of the slow consumer with ResourceExhausted
+	DisconnectSlow
+	// SpillToStorage leaves messages which do not fit to the message store,
+	// the stream replays them from there once it drained its queue.
+	// Other events which do not fit are dropped.
+	SpillToStorage
+)
+
+// ParseOverflowPolicy parses the policy names accepted on the command line
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch name {
+	case "drop-oldest":
+		return DropOldest, nil
+	case "disconnect":
+		return DisconnectSlow, nil
+	case "spill":
+		return SpillToStorage, nil
+	default:
+		return 0, fmt.Errorf("unknown overflow policy: %s", name)
+	}
+}
+
+// Session is the live delivery stream of a connected user.
+// Events are buffered in a bounded outbound queue.
+type Session struct {
+	User string
+
+	backlog   []*pb.ServerEvent
+	mu        sync.Mutex
+	spilled   map[string]*pb.Message
+	events    chan *pb.ServerEvent
+	done      chan struct{}
+	err       error
+	closeOnce sync.Once
+}
+
+func newSession(user string, queueSize int) *Session {
+	return &Session{
+		User:   user,
+		events: make(chan *pb.ServerEvent, queueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Events returns the queue events for the session user are delivered to
+func (s *Session) Events() <-chan *pb.ServerEvent {
+	return s.events
+}
+
+// Backlog returns the events queued while the user was offline,
+// they have to be sent before any event of the queue
+func (s *Session) Backlog() []*pb.ServerEvent {
+	return s.backlog
+}
+
+// Done is closed once the session is torn down
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reason the session was closed by the server, nil otherwise
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// enqueue puts event on the outbound queue without blocking and
+// returns how many events were dropped by the overflow policy
+func (s *Session) enqueue(event *pb.ServerEvent, policy OverflowPolicy) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return 0, status.Errorf(codes.Unavailable, "user %s is offline", s.User)
+	default:
+	}
+
+	msg := event.GetMessage()
+	if policy == SpillToStorage && msg != nil {
+		// later messages of a spilled conversation must not overtake the replay
+		if _, ok := s.spilled[spillKey(msg)]; ok {
+			return 0, nil
+		}
+	}
+
+	dropped := 0
+	for {
+		select {
+		case s.events <- event:
+			return dropped, nil
+		default:
+		}
+
+		if policy == DisconnectSlow {
+			s.err = status.Errorf(codes.ResourceExhausted, "outbound queue of %s is full", s.User)
+			s.close()
+
+			return dropped + 1, s.err
+		}
+
+		if policy == SpillToStorage {
+			if msg == nil {
+				return 1, nil
+			}
+
+			if s.spilled == nil {
+				s.spilled = make(map[string]*pb.Message)
+			}
+
+			s.spilled[spillKey(msg)] = msg
+
+			return 0, nil
+		}
+
+		select {
+		case <-s.events:
+			dropped++
+		default:
+		}
+	}
+}
+
+// TakeSpilled returns the first message of every conversation spilled by the
+// SpillToStorage policy since the last call. The stream has to call it only
+// once its queue is empty and replay the conversations from storage starting
+// with these messages, later messages of them are queued again afterwards.
+func (s *Session) TakeSpilled() []*pb.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.spilled) == 0 {
+		return nil
+	}
+
+	spilled := make([]*pb.Message, 0, len(s.spilled))
+	for _, msg := range s.spilled {
+		spilled = append(spilled, msg)
+	}
+
+	s.spilled = nil
+
+	return spilled
+}
+
+// spillKey identifies the conversation of msg among the messages of one receiver
+func spillKey(msg *pb.Message) string {
+	if msg.GetChannel().GetType() == pb.ChannelType_GROUP {
+		return "group/" + msg.GetChannel().GetName()
+	}
+
+	return "direct/" + msg.GetChannel().GetName() + "/" + msg.GetSender()
+}
+
+// closeWithError tears down the session with err as the reason
+func (s *Session) closeWithError(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+
+	s.close()
+}
+
+func (s *Session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
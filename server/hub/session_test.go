@@ -0,0 +1,141 @@
+package hub
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+// queuedIDs takes the queued events of session and returns their message ids
+func queuedIDs(session *Session) []string {
+	var ids []string
+	for len(session.Events()) > 0 {
+		ids = append(ids, (<-session.Events()).GetMessage().GetId())
+	}
+
+	return ids
+}
+
+func TestDropOldestKeepsNewestEvents(t *testing.T) {
+	h := New(Config{QueueSize: 2, Overflow: DropOldest})
+
+	session, err := h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	defer h.Disconnect(session)
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		err = h.Deliver("bob", messageEvent(id, "alice"))
+		if err != nil {
+			t.Fatalf("Deliver %s: %v", id, err)
+		}
+	}
+
+	if ids := queuedIDs(session); len(ids) != 2 || ids[0] != "m2" || ids[1] != "m3" {
+		t.Fatalf("queued %v, want [m2 m3]", ids)
+	}
+
+	if dropped := h.DroppedMessages()["bob"]; dropped != 1 {
+		t.Fatalf("%d events dropped, want 1", dropped)
+	}
+}
+
+func TestDisconnectSlowClosesSession(t *testing.T) {
+	h := New(Config{QueueSize: 1, Overflow: DisconnectSlow})
+
+	session, err := h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	err = h.Deliver("bob", messageEvent("m1", "alice"))
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	err = h.Deliver("bob", messageEvent("m2", "alice"))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Deliver to a full queue = %v, want ResourceExhausted", err)
+	}
+
+	select {
+	case <-session.Done():
+	default:
+		t.Fatal("session of the slow consumer is still open")
+	}
+
+	if h.IsConnected("bob") {
+		t.Fatal("slow consumer is still connected")
+	}
+}
+
+func TestSpillToStorageKeepsConversationOrder(t *testing.T) {
+	h := New(Config{QueueSize: 2, Overflow: SpillToStorage})
+
+	session, err := h.Connect("bob")
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	defer h.Disconnect(session)
+
+	for _, id := range []string{"m1", "m2", "m3", "m4"} {
+		err = h.DeliverMessage("bob", messageEvent(id, "alice"))
+		if err != nil {
+			t.Fatalf("DeliverMessage %s: %v", id, err)
+		}
+	}
+
+	// events of other kinds do not fit and are dropped
+	err = h.Deliver("bob", &pb.ServerEvent{Event: &pb.ServerEvent_Control{Control: &pb.Control{}}})
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	if ids := queuedIDs(session); len(ids) != 2 || ids[0] != "m1" || ids[1] != "m2" {
+		t.Fatalf("queued %v, want [m1 m2]", ids)
+	}
+
+	// the queue has room again, but later messages of the conversation wait for the replay
+	err = h.DeliverMessage("bob", messageEvent("m5", "alice"))
+	if err != nil {
+		t.Fatalf("DeliverMessage: %v", err)
+	}
+
+	err = h.DeliverMessage("bob", messageEvent("c1", "carol"))
+	if err != nil {
+		t.Fatalf("DeliverMessage: %v", err)
+	}
+
+	if ids := queuedIDs(session); len(ids) != 1 || ids[0] != "c1" {
+		t.Fatalf("queued %v, want only the message of the other conversation", ids)
+	}
+
+	spilled := session.TakeSpilled()
+	if len(spilled) != 1 || spilled[0].GetId() != "m3" {
+		t.Fatalf("spilled %v, want the first spilled message m3", spilled)
+	}
+
+	if h.DroppedMessages()["bob"] != 1 {
+		t.Fatalf("%d events dropped, want only the control event", h.DroppedMessages()["bob"])
+	}
+
+	// spilled messages are still redelivered until they are acked
+	if acked := h.Ack("bob", []string{"m3", "m4", "m5"}); len(acked) != 3 {
+		t.Fatalf("%d spilled messages pending, want 3", len(acked))
+	}
+
+	err = h.DeliverMessage("bob", messageEvent("m6", "alice"))
+	if err != nil {
+		t.Fatalf("DeliverMessage: %v", err)
+	}
+
+	if ids := queuedIDs(session); len(ids) != 1 || ids[0] != "m6" {
+		t.Fatalf("queued %v after the replay, want [m6]", ids)
+	}
+}
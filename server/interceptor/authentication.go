@@ -4,37 +4,77 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
-	metadata2 "github.com/vitthalaa/go-grpc-chat/server/metadata"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
 )
 
+// publicMethods can be called without authentication. They manage the password accounts
+// of token authentication and are not served when calls are authenticated otherwise.
+var publicMethods = map[string]bool{
+	pb.ChatService_Register_FullMethodName: true,
+	pb.ChatService_Login_FullMethodName:    true,
+}
+
+// Auth authenticates every call except public methods with its Authenticator
+// and provides the resolved principal in the context of the call.
+// Principals whose name is not a valid user name are rejected.
+type Auth struct {
+	authenticator auth.Authenticator
+	accounts      bool
+}
+
+// NewAuth returns the interceptors authenticating calls by authenticator. The public methods are only
+// served with accounts, since anybody could register names otherwise which the authenticator hands out.
+func NewAuth(authenticator auth.Authenticator, accounts bool) *Auth {
+	return &Auth{
+		authenticator: authenticator,
+		accounts:      accounts,
+	}
+}
+
 // AuthUnaryInterceptor is authentication interceptor for non-stream grpc server methods
-func AuthUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	md, err := metadata2.GetMetaDataWithUser(ctx)
+func (a *Auth) AuthUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	ctx, err = a.authenticate(ctx, info.FullMethod)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx = metadata.NewIncomingContext(ctx, md)
-
 	return handler(ctx, req)
 }
 
 // AuthStreamInterceptor is authentication interceptor for stream grpc server methods
-func AuthStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	// connect method don't need authentication
-	if info.FullMethod == pb.ChatService_Connect_FullMethodName {
-		return handler(srv, ss)
+func (a *Auth) AuthStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
 	}
 
-	md, err := metadata2.GetMetaDataWithUser(ss.Context())
+	return handler(srv, newStreamWrapper(ss, ctx))
+}
+
+// authenticate returns ctx with the principal of the call, public methods get no principal
+func (a *Auth) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if publicMethods[method] {
+		if !a.accounts {
+			return nil, status.Error(codes.FailedPrecondition, "accounts are not used by the authentication of this server")
+		}
+
+		return ctx, nil
+	}
+
+	principal, err := a.authenticator.Authenticate(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	ctx := metadata.NewIncomingContext(ss.Context(), md)
+	// names of external principals must be valid user names as well, since they key stored conversations
+	err = auth.ValidateUsername(principal.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid principal name: %v", err)
+	}
 
-	return handler(srv, newStreamWrapper(ss, ctx))
+	return auth.NewContext(ctx, principal), nil
 }
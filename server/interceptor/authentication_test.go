@@ -0,0 +1,82 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+)
+
+// staticAuthenticator authenticates every call as name
+type staticAuthenticator string
+
+func (a staticAuthenticator) Authenticate(context.Context) (auth.Principal, error) {
+	return auth.Principal{Name: string(a), Method: "static"}, nil
+}
+
+func callAs(name string) (string, error) {
+	a := NewAuth(staticAuthenticator(name), true)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.ChatService_ListChannels_FullMethodName}
+
+	res, err := a.AuthUnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		p, _ := auth.FromContext(ctx)
+		return p.Name, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return res.(string), nil
+}
+
+func TestAuthRejectsInvalidPrincipalNames(t *testing.T) {
+	for _, name := range []string{"a/b", "alice/bob", "x", "bob@example.com", ""} {
+		_, err := callAs(name)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("principal %q: %v, want Unauthenticated", name, err)
+		}
+	}
+}
+
+func TestAuthProvidesPrincipal(t *testing.T) {
+	name, err := callAs("alice.b-c_d")
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	if name != "alice.b-c_d" {
+		t.Fatalf("principal in context = %q, want alice.b-c_d", name)
+	}
+}
+
+// rejectingAuthenticator fails every authentication
+type rejectingAuthenticator struct{}
+
+func (rejectingAuthenticator) Authenticate(context.Context) (auth.Principal, error) {
+	return auth.Principal{}, status.Error(codes.Unauthenticated, "no credentials")
+}
+
+func TestAuthServesAccountMethodsOnlyWithAccounts(t *testing.T) {
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		return "served", nil
+	}
+
+	for _, method := range []string{pb.ChatService_Register_FullMethodName, pb.ChatService_Login_FullMethodName} {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+
+		res, err := NewAuth(rejectingAuthenticator{}, true).AuthUnaryInterceptor(context.Background(), nil, info, handler)
+		if err != nil || res != "served" {
+			t.Errorf("%s with accounts = %v, %v, want it served without authentication", method, res, err)
+		}
+
+		_, err = NewAuth(staticAuthenticator("alice"), false).AuthUnaryInterceptor(context.Background(), nil, info, handler)
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("%s without accounts: %v, want FailedPrecondition", method, err)
+		}
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"google.golang.org/grpc"
 )
 
-// streamWrapper is a wrapper of grpc.ServerStream for providing authenticated user metadata in context
+// streamWrapper is a wrapper of grpc.ServerStream for providing the authenticated principal in context
 type streamWrapper struct {
 	grpc.ServerStream
 	ctx context.Context
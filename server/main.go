@@ -1,30 +1,155 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"expvar"
+	"flag"
+	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 
 	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/blob"
+	"github.com/vitthalaa/go-grpc-chat/server/certs"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
 	"github.com/vitthalaa/go-grpc-chat/server/interceptor"
 	"github.com/vitthalaa/go-grpc-chat/server/service"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+var (
+	queueSize        = flag.Int("queue-size", 64, "number of messages buffered per connected user")
+	overflowPolicy   = flag.String("overflow-policy", "drop-oldest", "policy for full user queues: drop-oldest, disconnect or spill (replay messages from storage once the client caught up)")
+	pendingQueueSize = flag.Int("pending-queue-size", 100, "number of unacknowledged messages kept per user for redelivery")
+	pendingTTL       = flag.Duration("pending-ttl", 24*time.Hour, "how long unacknowledged messages are kept for redelivery")
+	dbPath           = flag.String("db", "", "path of the bolt database file, everything is kept in memory when empty")
+	debugAddr        = flag.String("debug-addr", "", "address serving expvar counters on /debug/vars, disabled when empty")
+	blobDir          = flag.String("blob-dir", "attachments", "directory storing the content of attachments")
+	maxAttachment    = flag.Int64("max-attachment-size", 10<<20, "maximum size of an attachment in bytes")
+	attachmentTypes  = flag.String("attachment-types", "image/*,application/pdf,text/plain", "comma separated accepted attachment media types, type/* accepts all subtypes")
+	tokenKeyFile     = flag.String("token-key-file", "", "file holding the key signing session tokens, a random key is used when empty")
+	tokenTTL         = flag.Duration("token-ttl", 24*time.Hour, "how long session tokens are valid")
+	authMode         = flag.String("auth", "token", "authentication of calls: token, api-key, mtls, proxy-header or oidc, mtls requires -tls-client-ca")
+	apiKeysFile      = flag.String("api-keys-file", "", "file of api keys for -auth api-key, one key and its user per line")
+	proxyHeader      = flag.String("proxy-header", "x-forwarded-user", "metadata header carrying the user for -auth proxy-header")
+	trustedProxies   = flag.String("trusted-proxies", "127.0.0.1/32,::1/128", "comma separated networks of proxies trusted by -auth proxy-header")
+	oidcIssuer       = flag.String("oidc-issuer", "", "issuer URL of the tokens of -auth oidc")
+	oidcAudience     = flag.String("oidc-audience", "", "audience the tokens of -auth oidc must be issued for, usually the client id")
+	oidcUserClaim    = flag.String("oidc-username-claim", "preferred_username", "claim of the tokens of -auth oidc holding the user name")
+	oidcKeyCacheTTL  = flag.Duration("oidc-key-cache-ttl", time.Hour, "how long the key set of the oidc issuer is cached")
+	tlsCert          = flag.String("tls-cert", "", "certificate file of the server, plaintext is served when empty")
+	tlsKey           = flag.String("tls-key", "", "private key file of the server certificate")
+	tlsClientCA      = flag.String("tls-client-ca", "", "CA file verifying required client certificates, enables mutual TLS")
+	keepaliveTime    = flag.Duration("keepalive-time", 30*time.Second, "idle time after which the server pings a client connection")
+	keepaliveTimeout = flag.Duration("keepalive-timeout", 10*time.Second, "how long a ping may stay unanswered before the connection is closed")
 )
 
 func main() {
+	flag.Parse()
+
+	overflow, err := hub.ParseOverflowPolicy(*overflowPolicy)
+	if err != nil {
+		log.Fatalf("Invalid flag: %v", err)
+	}
+
+	chatHub := hub.New(hub.Config{
+		QueueSize:        *queueSize,
+		Overflow:         overflow,
+		PendingQueueSize: *pendingQueueSize,
+		PendingTTL:       *pendingTTL,
+	})
+
+	expvar.Publish("dropped_messages", expvar.Func(func() any {
+		return chatHub.DroppedMessages()
+	}))
+
+	if *debugAddr != "" {
+		go func() {
+			log.Println(http.ListenAndServe(*debugAddr, nil))
+		}()
+	}
+
+	chatStore, err := newStore(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+
+	defer chatStore.Close()
+
+	err = service.Restore(context.Background(), chatHub, chatStore)
+	if err != nil {
+		log.Fatalf("Failed to restore users and groups: %v", err)
+	}
+
+	blobs, err := blob.NewFileStore(*blobDir)
+	if err != nil {
+		log.Fatalf("Failed to open blob store: %v", err)
+	}
+
 	lis, err := net.Listen("tcp", "localhost:5400")
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
+	tokenKey, err := loadTokenKey(*tokenKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load token key: %v", err)
+	}
+
+	tokens := auth.NewTokens(tokenKey, *tokenTTL)
+
+	authenticator, err := newAuthenticator(*authMode, tokens, chatStore)
+	if err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+
+	// Register and Login are only served when their accounts authenticate the calls
+	authInc := interceptor.NewAuth(authenticator, *authMode == "token")
+
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(interceptor.AuthUnaryInterceptor),
-		grpc.StreamInterceptor(interceptor.AuthStreamInterceptor),
+		grpc.UnaryInterceptor(authInc.AuthUnaryInterceptor),
+		grpc.StreamInterceptor(authInc.AuthStreamInterceptor),
+		// dead connections are detected by pings, so that their sessions are released
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    *keepaliveTime,
+			Timeout: *keepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	}
+
+	creds, err := newServerCredentials()
+	if err != nil {
+		log.Fatalf("Failed to set up TLS: %v", err)
+	}
+
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
 	}
 
 	grpcServer := grpc.NewServer(opts...)
 
-	chatSvc := service.NewChatService()
+	chatSvc := service.NewChatService(chatHub, chatStore, service.Config{
+		Blobs: blobs,
+		AttachmentLimits: service.AttachmentLimits{
+			MaxSize:      *maxAttachment,
+			ContentTypes: strings.Split(*attachmentTypes, ","),
+		},
+		Tokens: tokens,
+	})
 	pb.RegisterChatServiceServer(grpcServer, chatSvc)
 
 	err = grpcServer.Serve(lis)
@@ -32,3 +157,124 @@ func main() {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
+
+func newStore(path string) (store.Store, error) {
+	if path == "" {
+		return store.NewMemoryStore(), nil
+	}
+
+	return store.NewBoltStore(path)
+}
+
+// newServerCredentials returns the TLS credentials of the tls flags, nil for plaintext.
+// Certificate files are reloaded when they change.
+func newServerCredentials() (credentials.TransportCredentials, error) {
+	if *tlsCert == "" && *tlsKey == "" {
+		if *tlsClientCA != "" || *authMode == "mtls" {
+			return nil, fmt.Errorf("mutual TLS requires -tls-cert and -tls-key")
+		}
+
+		return nil, nil
+	}
+
+	if *tlsCert == "" || *tlsKey == "" {
+		return nil, fmt.Errorf("both -tls-cert and -tls-key are required")
+	}
+
+	if *authMode == "mtls" && *tlsClientCA == "" {
+		return nil, fmt.Errorf("mtls authentication requires -tls-client-ca")
+	}
+
+	reloader, err := certs.NewReloader(*tlsCert, *tlsKey, *tlsClientCA)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(reloader.ServerConfig()), nil
+}
+
+// newAuthenticator returns the Authenticator of mode, session tokens are verified against accounts
+func newAuthenticator(mode string, tokens *auth.Tokens, accounts store.AccountStore) (auth.Authenticator, error) {
+	switch mode {
+	case "token":
+		return auth.NewTokenAuthenticator(tokens, accounts), nil
+	case "api-key":
+		if *apiKeysFile == "" {
+			return nil, fmt.Errorf("api-key authentication requires -api-keys-file")
+		}
+
+		keys, err := auth.LoadAPIKeys(*apiKeysFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return auth.NewAPIKeyAuthenticator(keys), nil
+	case "mtls":
+		return auth.NewCertAuthenticator(), nil
+	case "proxy-header":
+		proxies, err := parseNetworks(*trustedProxies)
+		if err != nil {
+			return nil, err
+		}
+
+		return auth.NewProxyHeaderAuthenticator(strings.ToLower(*proxyHeader), proxies), nil
+	case "oidc":
+		if *oidcIssuer == "" || *oidcAudience == "" {
+			return nil, fmt.Errorf("oidc authentication requires -oidc-issuer and -oidc-audience")
+		}
+
+		return auth.NewOIDCAuthenticator(auth.OIDCConfig{
+			Issuer:        *oidcIssuer,
+			Audience:      *oidcAudience,
+			UsernameClaim: *oidcUserClaim,
+			KeyCacheTTL:   *oidcKeyCacheTTL,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown authentication %q", mode)
+	}
+}
+
+// parseNetworks parses a comma separated list of CIDR networks
+func parseNetworks(list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(list, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// loadTokenKey reads the token signing key from path, without a path a random key
+// is generated and tokens become invalid when the server restarts
+func loadTokenKey(path string) ([]byte, error) {
+	if path == "" {
+		log.Println("No token key file given, using a random key")
+
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+
+		return key, err
+	}
+
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key = bytes.TrimSpace(key)
+	if len(key) < 32 {
+		return nil, fmt.Errorf("token key must be at least 32 bytes, got %d", len(key))
+	}
+
+	return key, nil
+}
@@ -2,7 +2,7 @@ package metadata
 
 import (
 	"context"
-	"fmt"
+	"strings"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -10,38 +10,40 @@ import (
 )
 
 var (
-	userNameKey      = "username"
 	authorizationKey = "authorization"
+	bearerPrefix     = "Bearer "
 )
 
-func GetMetaDataWithUser(ctx context.Context) (metadata.MD, error) {
+// GetBearerToken returns the token of the bearer authorization header
+func GetBearerToken(ctx context.Context) (string, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "no metadata")
+		return "", status.Error(codes.Unauthenticated, "no metadata")
 	}
 
-	username := md.Get(authorizationKey)
-	if len(username) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "no authorization")
+	authorization := md.Get(authorizationKey)
+	if len(authorization) == 0 {
+		return "", status.Error(codes.Unauthenticated, "no authorization")
 	}
 
-	fmt.Println("metadata username: ", username[0])
-
-	md.Set(userNameKey, username[0])
+	if !strings.HasPrefix(authorization[0], bearerPrefix) {
+		return "", status.Error(codes.Unauthenticated, "authorization is not a bearer token")
+	}
 
-	return md, nil
+	return strings.TrimPrefix(authorization[0], bearerPrefix), nil
 }
 
-func GetUserName(ctx context.Context) string {
+// Get returns the first incoming metadata value of key, empty when there is none
+func Get(ctx context.Context, key string) string {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		return ""
 	}
 
-	username := md.Get(userNameKey)
-	if len(username) == 0 {
+	values := md.Get(key)
+	if len(values) == 0 {
 		return ""
 	}
 
-	return username[0]
+	return values[0]
 }
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/blob"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+const (
+	// downloadChunkSize is the size of the content chunks sent by DownloadAttachment
+	downloadChunkSize = 64 * 1024
+	// maxAttachmentName is the maximum length of an attachment name in bytes
+	maxAttachmentName = 255
+	// maxMessageAttachments is the maximum number of attachments of a message
+	maxMessageAttachments = 10
+)
+
+// AttachmentLimits restricts the attachments users can upload
+type AttachmentLimits struct {
+	// MaxSize is the maximum size of an attachment in bytes
+	MaxSize int64
+	// ContentTypes are the accepted media types, "type/*" accepts all subtypes of type
+	ContentTypes []string
+}
+
+// allows reports whether contentType is one of the accepted media types
+func (l AttachmentLimits) allows(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, accepted := range l.ContentTypes {
+		if accepted == mediaType || (strings.HasSuffix(accepted, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(accepted, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *ChatService) UploadAttachment(stream pb.ChatService_UploadAttachmentServer) error {
+	ctx := stream.Context()
+
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "attachment info is required")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	info := req.GetInfo()
+	if info == nil {
+		return status.Error(codes.InvalidArgument, "attachment info must be sent first")
+	}
+
+	err = s.validateAttachment(info)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conversationKey(user, info.GetChannel())
+	if err != nil {
+		return err
+	}
+
+	attachment := &pb.Attachment{
+		Id: newID(),
+		Channel: &pb.Channel{
+			Type: info.GetChannel().GetType(),
+			Name: info.GetChannel().GetName(),
+		},
+		Name:        info.GetName(),
+		ContentType: info.GetContentType(),
+		Size:        info.GetSize(),
+		Uploader:    user,
+		Time:        timestamppb.New(time.Now()),
+	}
+
+	content := &chunkReader{
+		stream: stream,
+		limit:  info.GetSize(),
+	}
+
+	err = s.blobs.Put(ctx, attachment.GetId(), content)
+	if err == nil && content.n != info.GetSize() {
+		err = status.Errorf(codes.InvalidArgument, "received %d bytes of %d", content.n, info.GetSize())
+	}
+
+	if err != nil {
+		_ = s.blobs.Delete(ctx, attachment.GetId())
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+
+		log.Printf("failed to store attachment: %v", err)
+		return status.Error(codes.Internal, "failed to store attachment")
+	}
+
+	err = s.attachments.PutAttachment(ctx, attachment)
+	if err != nil {
+		_ = s.blobs.Delete(ctx, attachment.GetId())
+		log.Printf("failed to store attachment info: %v", err)
+		return status.Error(codes.Internal, "failed to store attachment")
+	}
+
+	return stream.SendAndClose(attachment)
+}
+
+func (s *ChatService) DownloadAttachment(req *pb.DownloadAttachmentRequest, stream pb.ChatService_DownloadAttachmentServer) error {
+	ctx := stream.Context()
+
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	id := req.GetAttachmentId()
+
+	attachment, err := s.attachments.Attachment(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return status.Errorf(codes.NotFound, "attachment %s not found", id)
+	}
+
+	if err != nil {
+		log.Printf("failed to get attachment: %v", err)
+		return status.Error(codes.Internal, "failed to get attachment")
+	}
+
+	// attachments are visible to the same users as the messages of their channel
+	_, err = s.checkParticipant(user, attachment.GetUploader(), attachment.GetChannel(), "attachment "+id)
+	if err != nil {
+		return err
+	}
+
+	content, err := s.blobs.Get(ctx, id)
+	if errors.Is(err, blob.ErrNotFound) {
+		return status.Errorf(codes.NotFound, "attachment %s not found", id)
+	}
+
+	if err != nil {
+		log.Printf("failed to open attachment: %v", err)
+		return status.Error(codes.Internal, "failed to get attachment")
+	}
+
+	defer content.Close()
+
+	err = stream.Send(&pb.DownloadAttachmentResponse{
+		Data: &pb.DownloadAttachmentResponse_Info{Info: attachment},
+	})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, downloadChunkSize)
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			sendErr := stream.Send(&pb.DownloadAttachmentResponse{
+				Data: &pb.DownloadAttachmentResponse_Chunk{Chunk: buf[:n]},
+			})
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			log.Printf("failed to read attachment: %v", err)
+			return status.Error(codes.Internal, "failed to read attachment")
+		}
+	}
+}
+
+// validateAttachment checks the declared attachment info against the limits
+func (s *ChatService) validateAttachment(info *pb.Attachment) error {
+	if info.GetName() == "" || len(info.GetName()) > maxAttachmentName || strings.ContainsAny(info.GetName(), `/\`) {
+		return status.Errorf(codes.InvalidArgument, "invalid attachment name %q", info.GetName())
+	}
+
+	if info.GetSize() <= 0 {
+		return status.Error(codes.InvalidArgument, "attachment size is required")
+	}
+
+	if info.GetSize() > s.attachmentLimits.MaxSize {
+		return status.Errorf(codes.InvalidArgument, "attachment is larger than %d bytes", s.attachmentLimits.MaxSize)
+	}
+
+	if !s.attachmentLimits.allows(info.GetContentType()) {
+		return status.Errorf(codes.InvalidArgument, "content type %q is not accepted", info.GetContentType())
+	}
+
+	return nil
+}
+
+// messageAttachments returns the attachments ids after checking sender uploaded them to the conversation key
+func (s *ChatService) messageAttachments(ctx context.Context, key, sender string, ids []string) ([]*pb.Attachment, error) {
+	if len(ids) > maxMessageAttachments {
+		return nil, status.Errorf(codes.InvalidArgument, "a message can have at most %d attachments", maxMessageAttachments)
+	}
+
+	attachments := make([]*pb.Attachment, 0, len(ids))
+	for _, id := range ids {
+		attachment, err := s.attachments.Attachment(ctx, id)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && store.ChannelKey(attachment.GetChannel(), attachment.GetUploader()) != key) {
+			return nil, status.Errorf(codes.NotFound, "attachment %s not found", id)
+		}
+
+		if err != nil {
+			log.Printf("failed to get attachment: %v", err)
+			return nil, status.Error(codes.Internal, "failed to store message")
+		}
+
+		if attachment.GetUploader() != sender {
+			return nil, status.Errorf(codes.PermissionDenied, "attachment %s was uploaded by another user", id)
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+// chunkReader reads the content chunks of an upload stream, at most limit bytes are accepted
+type chunkReader struct {
+	stream pb.ChatService_UploadAttachmentServer
+	limit  int64
+	n      int64
+	chunk  []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		if _, ok := req.GetData().(*pb.UploadAttachmentRequest_Chunk); !ok {
+			return 0, status.Error(codes.InvalidArgument, "attachment info can only be sent first")
+		}
+
+		r.chunk = req.GetChunk()
+		r.n += int64(len(r.chunk))
+		if r.n > r.limit {
+			return 0, status.Errorf(codes.InvalidArgument, "attachment is larger than the declared %d bytes", r.limit)
+		}
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+
+	return n, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/blob"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// uploadStream feeds requests to UploadAttachment and records the stored attachment
+type uploadStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	requests   []*pb.UploadAttachmentRequest
+	attachment *pb.Attachment
+}
+
+func (s *uploadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *uploadStream) Recv() (*pb.UploadAttachmentRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+
+	return req, nil
+}
+
+func (s *uploadStream) SendAndClose(attachment *pb.Attachment) error {
+	s.attachment = attachment
+	return nil
+}
+
+// downloadStream records the responses of DownloadAttachment
+type downloadStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	info    *pb.Attachment
+	content bytes.Buffer
+}
+
+func (s *downloadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *downloadStream) Send(res *pb.DownloadAttachmentResponse) error {
+	if info := res.GetInfo(); info != nil {
+		s.info = info
+	}
+
+	s.content.Write(res.GetChunk())
+
+	return nil
+}
+
+// newAttachmentService returns a service accepting images and text of up to 10 bytes stored in dir
+func newAttachmentService(t *testing.T, dir string) *ChatService {
+	t.Helper()
+
+	blobs, err := blob.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+
+	return NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{
+		Blobs: blobs,
+		AttachmentLimits: AttachmentLimits{
+			MaxSize:      10,
+			ContentTypes: []string{"image/*", "text/plain"},
+		},
+	})
+}
+
+// upload returns the requests uploading chunks as attachment name of type contentType to receiver
+func upload(receiver, name, contentType string, size int64, chunks ...string) []*pb.UploadAttachmentRequest {
+	requests := []*pb.UploadAttachmentRequest{{
+		Data: &pb.UploadAttachmentRequest_Info{
+			Info: &pb.Attachment{
+				Channel:     &pb.Channel{Type: pb.ChannelType_USER, Name: receiver},
+				Name:        name,
+				ContentType: contentType,
+				Size:        size,
+			},
+		},
+	}}
+
+	for _, chunk := range chunks {
+		requests = append(requests, &pb.UploadAttachmentRequest{
+			Data: &pb.UploadAttachmentRequest_Chunk{Chunk: []byte(chunk)},
+		})
+	}
+
+	return requests
+}
+
+func TestUploadAttachmentEnforcesLimits(t *testing.T) {
+	dir := t.TempDir()
+	s := newAttachmentService(t, dir)
+	alice := connectAs(t, s, "alice")
+	connectAs(t, s, "bob")
+
+	tests := map[string][]*pb.UploadAttachmentRequest{
+		"too large":         upload("bob", "a.png", "image/png", 11, "01234567890"),
+		"type not accepted": upload("bob", "a.zip", "application/zip", 5, "hello"),
+		"path as name":      upload("bob", "../a.png", "image/png", 5, "hello"),
+		"more than size":    upload("bob", "a.png", "image/png", 5, "hello", "!"),
+		"less than size":    upload("bob", "a.png", "image/png", 6, "hello"),
+		"info not first":    upload("bob", "a.png", "image/png", 5, "hello")[1:],
+		"second info":       append(upload("bob", "a.png", "image/png", 5, "hel"), upload("bob", "a.png", "image/png", 5, "lo")...),
+	}
+
+	for name, requests := range tests {
+		err := s.UploadAttachment(&uploadStream{ctx: alice, requests: requests})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: UploadAttachment = %v, want InvalidArgument", name, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("rejected uploads left %d files behind", len(entries))
+	}
+}
+
+func TestAttachmentsAreSharedWithTheConversation(t *testing.T) {
+	s := newAttachmentService(t, t.TempDir())
+	alice := connectAs(t, s, "alice")
+	bob := connectAs(t, s, "bob")
+	carol := connectAs(t, s, "carol")
+
+	stream := &uploadStream{ctx: alice, requests: upload("bob", "a.png", "image/png", 5, "hel", "lo")}
+	err := s.UploadAttachment(stream)
+	if err != nil {
+		t.Fatalf("UploadAttachment: %v", err)
+	}
+
+	id := stream.attachment.GetId()
+
+	// only the uploader can send the attachment
+	_, err = s.sendMessage(bob, "bob", &pb.SendMessageRequest{Receiver: "alice", AttachmentIds: []string{id}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("send of an attachment of alice by bob = %v, want PermissionDenied", err)
+	}
+
+	msg, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", AttachmentIds: []string{id}})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	if len(msg.GetAttachments()) != 1 || msg.GetAttachments()[0].GetName() != "a.png" {
+		t.Fatalf("attachments = %v, want a.png", msg.GetAttachments())
+	}
+
+	ids := make([]string, maxMessageAttachments+1)
+	for i := range ids {
+		ids[i] = id
+	}
+
+	_, err = s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", AttachmentIds: ids})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("send of %d attachments = %v, want InvalidArgument", len(ids), err)
+	}
+
+	download := &downloadStream{ctx: bob}
+	err = s.DownloadAttachment(&pb.DownloadAttachmentRequest{AttachmentId: id}, download)
+	if err != nil {
+		t.Fatalf("DownloadAttachment: %v", err)
+	}
+
+	if download.info.GetSize() != 5 || download.content.String() != "hello" {
+		t.Fatalf("downloaded %v with %q, want hello", download.info, download.content.String())
+	}
+
+	err = s.DownloadAttachment(&pb.DownloadAttachmentRequest{AttachmentId: id}, &downloadStream{ctx: carol})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("DownloadAttachment by an outsider = %v, want NotFound", err)
+	}
+}
@@ -2,7 +2,8 @@ package service
 
 import (
 	"context"
-	"errors"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
@@ -14,134 +15,238 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
-	"github.com/vitthalaa/go-grpc-chat/server/metadata"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/blob"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
 )
 
-type Channel struct {
-	Type  pb.ChannelType
-	Name  string
-	Users []string
-}
-
 type ChatService struct {
 	pb.UnimplementedChatServiceServer
-	userChannels map[string]chan *pb.Message
-	channels     map[string]Channel
+	hub               *hub.Hub
+	messages          store.MessageStore
+	readMarkers       store.ReadMarkerStore
+	attachments       store.AttachmentStore
+	accounts          store.AccountStore
+	groups            store.GroupStore
+	blobs             blob.Store
+	attachmentLimits  AttachmentLimits
+	tokens            *auth.Tokens
+	conversationLocks stripedMutex
+	groupLocks        stripedMutex
+	typing            *typingTracker
 }
 
-func NewChatService() *ChatService {
+// Config holds the dependencies of the chat service besides the hub and the store
+type Config struct {
+	// Blobs keeps the content of attachments
+	Blobs blob.Store
+	// AttachmentLimits restricts uploaded attachments
+	AttachmentLimits AttachmentLimits
+	// Tokens issues the session tokens of Login
+	Tokens *auth.Tokens
+}
+
+func NewChatService(h *hub.Hub, st store.Store, cfg Config) *ChatService {
 	return &ChatService{
-		userChannels: make(map[string]chan *pb.Message),
-		channels:     make(map[string]Channel),
+		hub:              h,
+		messages:         st,
+		readMarkers:      st,
+		attachments:      st,
+		accounts:         st,
+		groups:           st,
+		blobs:            cfg.Blobs,
+		attachmentLimits: cfg.AttachmentLimits,
+		tokens:           cfg.Tokens,
+		typing:           newTypingTracker(),
 	}
 }
 
-var errUnimplemented = errors.New("not implemented")
-
 func (s *ChatService) Connect(req *pb.ConnectRequest, stream pb.ChatService_ConnectServer) error {
-	userName := req.GetUsername()
-	if _, ok := s.userChannels[userName]; ok {
-		return errors.New("already present")
+	// the stream is bound to the authenticated user, the requested name is only checked against it
+	userName := principalName(stream.Context())
+	if userName == "" {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
 	}
 
-	channel := Channel{
-		Type: pb.ChannelType_USER,
-		Name: userName,
+	if req.GetUsername() != "" && req.GetUsername() != userName {
+		return status.Errorf(codes.PermissionDenied, "can not connect as %s", req.GetUsername())
+	}
+
+	session, err := s.connect(userName)
+	if err != nil {
+		return err
 	}
 
-	s.channels[userName] = channel
+	defer s.disconnect(session)
 
-	msgChannel := make(chan *pb.Message)
-	s.userChannels[userName] = msgChannel
+	send := func(msg *pb.Message) error {
+		return stream.Send(&pb.ConnectResponse{
+			Response: &pb.ConnectResponse_Message{
+				Message: msg,
+			},
+		})
+	}
+
+	// the gap since the cursors is replayed first, messages of it which are
+	// also in the backlog or the live queue are skipped
+	sent := seqTracker{}
+
+	err = s.replay(stream.Context(), userName, req.GetCursors(), sent, send)
+	if err != nil {
+		log.Printf("failed to replay messages: %v", err)
+		return status.Error(codes.Internal, "failed to replay messages")
+	}
+
+	for _, event := range session.Backlog() {
+		if msg := event.GetMessage(); msg != nil && sent.next(msg) {
+			err = send(msg)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	for {
 		select {
 		case <-stream.Context().Done():
 			return nil
-		case msg := <-msgChannel:
-			fmt.Printf("GO ROUTINE (got message): %v \n", msg)
-			err := stream.Send(msg)
+		case <-session.Done():
+			return session.Err()
+		case event := <-session.Events():
+			if msg := event.GetMessage(); msg == nil {
+				// other events are only sent to clients asking for them
+				if req.GetEvents() {
+					err = stream.Send(&pb.ConnectResponse{
+						Response: &pb.ConnectResponse_Event{
+							Event: event,
+						},
+					})
+					if err != nil {
+						return err
+					}
+				}
+			} else if sent.next(msg) {
+				fmt.Printf("GO ROUTINE (got message): %v \n", msg)
+				err := send(msg)
+				if err != nil {
+					log.Default().Println(err)
+				}
+			}
+
+			err = s.replaySpilled(stream.Context(), session, sent, send)
 			if err != nil {
-				log.Default().Println(err)
+				return err
 			}
 		}
 	}
 }
 
 func (s *ChatService) CreateGroupChat(ctx context.Context, req *pb.CreateGroupChatRequest) (*emptypb.Empty, error) {
-	_, err := s.getAuthUser(ctx)
+	user, err := s.getAuthUser(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, errUnimplemented
+	// users and groups share one namespace, registered users are known to the hub
+	// before their account is written, so the hub rejects their names atomically
+	unlock := s.lockGroup(req.GetChannelName())
+	err = s.hub.CreateGroup(req.GetChannelName(), user)
+	if err == nil {
+		s.saveGroup(ctx, req.GetChannelName())
+	}
+	unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.postSystemEvent(ctx, req.GetChannelName(), pb.SystemEventType_GROUP_CREATED, user)
+
+	return &emptypb.Empty{}, nil
 }
 
 func (s *ChatService) JoinGroupChat(ctx context.Context, req *pb.JoinGroupChatRequest) (*emptypb.Empty, error) {
-	_, err := s.getAuthUser(ctx)
+	user, err := s.getAuthUser(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, errUnimplemented
-}
+	unlock := s.lockGroup(req.GetChannelName())
+	err = s.hub.JoinGroup(req.GetChannelName(), user)
+	if err == nil {
+		s.saveGroup(ctx, req.GetChannelName())
+	}
+	unlock()
 
-func (s *ChatService) LeaveGroupChat(ctx context.Context, req *pb.LeaveGroupChatRequest) (*emptypb.Empty, error) {
-	_, err := s.getAuthUser(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, errUnimplemented
+	s.postSystemEvent(ctx, req.GetChannelName(), pb.SystemEventType_MEMBER_JOINED, user)
+
+	return &emptypb.Empty{}, nil
 }
 
-func (s *ChatService) SendMessage(msgStream pb.ChatService_SendMessageServer) error {
-	sender, err := s.getAuthUser(msgStream.Context())
+func (s *ChatService) LeaveGroupChat(ctx context.Context, req *pb.LeaveGroupChatRequest) (*emptypb.Empty, error) {
+	user, err := s.getAuthUser(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := msgStream.Recv()
-	if err == io.EOF {
-		return nil
+	unlock := s.lockGroup(req.GetChannelName())
+	err = s.hub.LeaveGroup(req.GetChannelName(), user)
+	if err == nil {
+		s.saveGroup(ctx, req.GetChannelName())
 	}
+	unlock()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	channel, ok := s.channels[req.GetReceiver()]
-	if !ok {
-		return errors.New("invalid receiver")
-	}
+	s.postSystemEvent(ctx, req.GetChannelName(), pb.SystemEventType_MEMBER_LEFT, user)
+
+	return &emptypb.Empty{}, nil
+}
 
-	err = msgStream.SendAndClose(&emptypb.Empty{})
+func (s *ChatService) SendMessage(msgStream pb.ChatService_SendMessageServer) error {
+	sender, err := s.getAuthUser(msgStream.Context())
 	if err != nil {
 		return err
 	}
 
-	pbChannel := &pb.Channel{
-		Type: channel.Type,
-		Name: channel.Name,
-	}
+	res := &pb.SendMessageResponse{}
+
+	for index := int32(0); ; index++ {
+		req, err := msgStream.Recv()
+		if err == io.EOF {
+			return msgStream.SendAndClose(res)
+		}
 
-	if channel.Type == pb.ChannelType_USER {
-		err = s.sendUserMessage(channel.Name, sender, req.GetMessage(), pbChannel)
 		if err != nil {
 			return err
 		}
 
-		return nil
-	}
-
-	for _, user := range channel.Users {
-		err = s.sendUserMessage(user, sender, req.GetMessage(), pbChannel)
+		msg, err := s.sendMessage(msgStream.Context(), sender, req)
 		if err != nil {
-			log.Println(err)
+			st := status.Convert(err)
+			res.Errors = append(res.Errors, &pb.SendMessageError{
+				Index:   index,
+				Code:    int32(st.Code()),
+				Message: st.Message(),
+			})
+
+			continue
 		}
-	}
 
-	return nil
+		res.Accepted++
+		res.Sent = append(res.Sent, &pb.SentMessage{
+			Index: index,
+			Id:    msg.GetId(),
+			Seq:   msg.GetSeq(),
+		})
+	}
 }
 
 func (s *ChatService) ListChannels(ctx context.Context, req *emptypb.Empty) (*pb.ListChannelsResponse, error) {
@@ -150,8 +255,12 @@ func (s *ChatService) ListChannels(ctx context.Context, req *emptypb.Empty) (*pb
 		return nil, err
 	}
 
-	resChan := make([]*pb.Channel, 0, len(s.channels)-1)
-	for _, c := range s.channels {
+	channels := s.hub.Channels()
+
+	resChan := make([]*pb.Channel, 0, len(channels))
+	unreadCounts := make(map[string]int64)
+	presence := make(map[string]*pb.Presence)
+	for _, c := range channels {
 		// not including user who requested list
 		if c.Name == user {
 			continue
@@ -161,36 +270,190 @@ func (s *ChatService) ListChannels(ctx context.Context, req *emptypb.Empty) (*pb
 			Type: c.Type,
 			Name: c.Name,
 		})
+
+		unread, ok, err := s.unreadCount(ctx, user, c)
+		if err != nil {
+			log.Printf("failed to count unread messages: %v", err)
+			return nil, status.Error(codes.Internal, "failed to list channels")
+		}
+
+		if ok {
+			unreadCounts[c.Name] = unread
+		}
+
+		if c.Type == pb.ChannelType_USER {
+			p, _ := s.hub.Presence(c.Name)
+			presence[c.Name] = presenceProto(c.Name, p)
+		}
 	}
 
 	return &pb.ListChannelsResponse{
-		Channels: resChan,
+		Channels:     resChan,
+		UnreadCounts: unreadCounts,
+		Presence:     presence,
 	}, nil
 }
 
-func (s *ChatService) sendUserMessage(user, sender, msg string, channel *pb.Channel) error {
-	msgChannel, ok := s.userChannels[user]
+// sendMessage stores a single message of sender and delivers it to the receiver user or group.
+// The returned message carries the server assigned id and sequence number.
+func (s *ChatService) sendMessage(ctx context.Context, sender string, req *pb.SendMessageRequest) (*pb.Message, error) {
+	channel, ok := s.hub.Channel(req.GetReceiver())
 	if !ok {
-		return errors.New("invalid receiver")
+		return nil, status.Errorf(codes.NotFound, "invalid receiver %s", req.GetReceiver())
+	}
+
+	if channel.Type == pb.ChannelType_GROUP && !channel.HasUser(sender) {
+		return nil, status.Errorf(codes.PermissionDenied, "not a member of %s", channel.Name)
+	}
+
+	msg := &pb.Message{
+		Channel: &pb.Channel{
+			Type: channel.Type,
+			Name: channel.Name,
+		},
+		Sender: sender,
+		Time:   timestamppb.New(time.Now()),
+		Id:     newID(),
+	}
+
+	key := store.ChannelKey(msg.Channel, sender)
+
+	parentID, err := s.threadRoot(ctx, key, req.GetParentId())
+	if err != nil {
+		return nil, err
+	}
+
+	msg.ParentId = parentID
+
+	msg.Attachments, err = s.messageAttachments(ctx, key, sender, req.GetAttachmentIds())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.setContent(ctx, key, req, msg)
+	if err != nil {
+		return nil, err
 	}
 
-	msgChannel <- &pb.Message{
-		Channel: channel,
-		Message: msg,
-		Sender:  sender,
-		Time:    timestamppb.New(time.Now()),
+	err = s.post(ctx, channel, key, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// post stores msg in the conversation key of channel and delivers it to the participants
+func (s *ChatService) post(ctx context.Context, channel hub.Channel, key string, msg *pb.Message) error {
+	sender := msg.GetSender()
+
+	// append and fan-out are serialized per conversation
+	// so that messages are delivered in sequence order
+	defer s.conversationLocks.lock(key).Unlock()
+
+	// message is durably written before fan-out
+	err := s.messages.Append(ctx, key, msg)
+	if err != nil {
+		log.Printf("failed to store message: %v", err)
+		return status.Error(codes.Internal, "failed to store message")
+	}
+
+	if msg.GetParentId() != "" {
+		s.countReply(ctx, msg.GetParentId(), 1)
+	}
+
+	// the message ends typing of sender in the channel
+	if s.typing.stop(sender, channel.Name) {
+		s.broadcastTyping(sender, channel, false)
+	}
+
+	// sending into a conversation means having read it
+	err = s.readMarkers.SetReadMarker(ctx, sender, key, msg.Seq)
+	if err != nil {
+		log.Printf("failed to set read marker: %v", err)
+	}
+
+	receivers := []string{channel.Name}
+	if channel.Type == pb.ChannelType_GROUP {
+		receivers = channel.Users
+	} else {
+		s.hub.AddContact(sender, channel.Name)
+	}
+
+	// message is already stored, failed deliveries don't fail the send
+	for _, user := range receivers {
+		err = s.deliverMessage(user, msg)
+		if err != nil {
+			log.Println(err)
+		}
 	}
 
 	return nil
 }
 
+// deliverMessage sends msg to user, it is redelivered on the next connect until user acks it
+func (s *ChatService) deliverMessage(user string, msg *pb.Message) error {
+	return s.hub.DeliverMessage(user, &pb.ServerEvent{
+		Event: &pb.ServerEvent_Message{
+			Message: msg,
+		},
+	})
+}
+
+// connect opens a session of user and announces the user online
+func (s *ChatService) connect(user string) (*hub.Session, error) {
+	session, err := s.hub.Connect(user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.broadcastPresence(user)
+
+	return session, nil
+}
+
+// disconnect tears down session, announces its user offline and ends all typing states of the user.
+// Nothing is announced for a session replaced by a new connection of its user.
+func (s *ChatService) disconnect(session *hub.Session) {
+	if !s.hub.Disconnect(session) {
+		return
+	}
+
+	s.broadcastPresence(session.User)
+
+	for _, name := range s.typing.stopUser(session.User) {
+		channel, ok := s.hub.Channel(name)
+		if ok {
+			s.broadcastTyping(session.User, channel, false)
+		}
+	}
+}
+
+// newID returns a random unique id of a message or an attachment
+func newID() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// principalName returns the user name of the authenticated principal of ctx, empty when there is none
+func principalName(ctx context.Context) string {
+	p, _ := auth.FromContext(ctx)
+
+	return p.Name
+}
+
 func (s *ChatService) getAuthUser(ctx context.Context) (string, error) {
-	username := metadata.GetUserName(ctx)
+	username := principalName(ctx)
 	if username == "" {
 		return "", status.Error(codes.Unauthenticated, "unauthenticated")
 	}
 
-	if _, ok := s.userChannels[username]; !ok {
+	if !s.hub.IsConnected(username) {
 		return "", status.Error(codes.Unauthenticated, "unauthenticated")
 	}
 
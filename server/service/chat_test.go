@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func TestChatServiceConcurrentClients(t *testing.T) {
+	const clients = 200
+
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	s := NewChatService(hub.New(hub.Config{}), st, Config{})
+
+	err := s.hub.CreateGroup("lobby", "owner")
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+
+	// every user is known before the clients start so that direct messages have a receiver
+	for i := 0; i < clients; i++ {
+		session, err := s.connect(fmt.Sprintf("user-%d", i))
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+
+		s.disconnect(session)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			user := fmt.Sprintf("user-%d", i)
+
+			session, err := s.connect(user)
+			if err != nil {
+				t.Errorf("connect %s: %v", user, err)
+				return
+			}
+
+			defer s.disconnect(session)
+
+			go func() {
+				for {
+					select {
+					case <-session.Done():
+						return
+					case <-session.Events():
+					}
+				}
+			}()
+
+			err = s.hub.JoinGroup("lobby", user)
+			if err != nil {
+				t.Errorf("JoinGroup %s: %v", user, err)
+			}
+
+			_, err = s.sendMessage(ctx, user, &pb.SendMessageRequest{Receiver: "lobby", Message: "hello " + user})
+			if err != nil {
+				t.Errorf("send to lobby: %v", err)
+			}
+
+			peer := fmt.Sprintf("user-%d", (i+1)%clients)
+			_, err = s.sendMessage(ctx, user, &pb.SendMessageRequest{Receiver: peer, Message: "hi " + peer})
+			if err != nil {
+				t.Errorf("send to %s: %v", peer, err)
+			}
+
+			err = s.setTyping(user, &pb.Typing{Channel: &pb.Channel{Type: pb.ChannelType_GROUP, Name: "lobby"}, Typing: true})
+			if err != nil {
+				t.Errorf("setTyping: %v", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	lobby, err := st.Fetch(ctx, "group/lobby", time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	seqs := make(map[int64]bool)
+	for _, msg := range lobby {
+		if seqs[msg.GetSeq()] {
+			t.Fatalf("sequence number %d assigned twice", msg.GetSeq())
+		}
+
+		seqs[msg.GetSeq()] = true
+	}
+
+	if len(lobby) != clients {
+		t.Errorf("lobby has %d messages, want %d", len(lobby), clients)
+	}
+
+	for i := 0; i < clients; i++ {
+		peer := fmt.Sprintf("user-%d", (i+1)%clients)
+		key := store.ChannelKey(&pb.Channel{Type: pb.ChannelType_USER, Name: peer}, fmt.Sprintf("user-%d", i))
+
+		direct, err := st.Fetch(ctx, key, time.Time{}, time.Now())
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+
+		if len(direct) == 0 {
+			t.Errorf("conversation %s has no messages", key)
+		}
+	}
+}
+
+// sendMessageStream feeds requests to SendMessage and records its response
+type sendMessageStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests []*pb.SendMessageRequest
+	res      *pb.SendMessageResponse
+}
+
+func (s *sendMessageStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *sendMessageStream) Recv() (*pb.SendMessageRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+
+	return req, nil
+}
+
+func (s *sendMessageStream) SendAndClose(res *pb.SendMessageResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestSendMessageProcessesEveryMessage(t *testing.T) {
+	st := store.NewMemoryStore()
+	s := NewChatService(hub.New(hub.Config{}), st, Config{})
+	connectAs(t, s, "bob")
+
+	stream := &sendMessageStream{
+		ctx: connectAs(t, s, "alice"),
+		requests: []*pb.SendMessageRequest{
+			{Receiver: "bob", Message: "first"},
+			{Receiver: "nobody", Message: "lost"},
+			{Receiver: "bob", Message: "second"},
+		},
+	}
+
+	err := s.SendMessage(stream)
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	res := stream.res
+	if res.GetAccepted() != 2 || len(res.GetSent()) != 2 {
+		t.Fatalf("response = %v, want 2 accepted messages", res)
+	}
+
+	if res.GetSent()[0].GetIndex() != 0 || res.GetSent()[1].GetIndex() != 2 {
+		t.Fatalf("sent = %v, want the messages at index 0 and 2", res.GetSent())
+	}
+
+	if len(res.GetErrors()) != 1 || res.GetErrors()[0].GetIndex() != 1 || codes.Code(res.GetErrors()[0].GetCode()) != codes.NotFound {
+		t.Fatalf("errors = %v, want NotFound at index 1", res.GetErrors())
+	}
+
+	key := store.ChannelKey(&pb.Channel{Type: pb.ChannelType_USER, Name: "bob"}, "alice")
+	stored, err := st.Fetch(context.Background(), key, time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	if len(stored) != 2 || stored[0].GetMessage() != "first" || stored[1].GetMessage() != "second" {
+		t.Fatalf("stored %v, want first and second", stored)
+	}
+}
+
+func TestSequenceNumbersArePerConversation(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	bob := connectAs(t, s, "bob")
+	connectAs(t, s, "carol")
+
+	send := func(ctx context.Context, sender, receiver string) *pb.Message {
+		t.Helper()
+
+		msg, err := s.sendMessage(ctx, sender, &pb.SendMessageRequest{Receiver: receiver, Message: "hi"})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+
+		return msg
+	}
+
+	// both directions of a direct channel are one conversation
+	messages := []*pb.Message{send(alice, "alice", "bob"), send(bob, "bob", "alice"), send(alice, "alice", "carol")}
+
+	for i, want := range []int64{1, 2, 1} {
+		if messages[i].GetSeq() != want {
+			t.Fatalf("message %d has seq %d, want %d", i, messages[i].GetSeq(), want)
+		}
+	}
+
+	ids := make(map[string]bool)
+	for _, msg := range messages {
+		if msg.GetId() == "" || ids[msg.GetId()] {
+			t.Fatalf("message id %q is empty or assigned twice", msg.GetId())
+		}
+
+		ids[msg.GetId()] = true
+	}
+
+	_, err := s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	// the creation of the group is its first message
+	if msg := send(alice, "alice", "team"); msg.GetSeq() != 2 {
+		t.Fatalf("group message has seq %d, want 2", msg.GetSeq())
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+const (
+	maxTextLength      = 4096
+	maxCaptionLength   = 1024
+	maxCardTitleLength = 256
+	maxCardActions     = 5
+)
+
+// setContent validates the content of req and sets it on msg together with its plain text rendering.
+// A request without content is sent as plain text, which may be empty for a message with attachments.
+func (s *ChatService) setContent(ctx context.Context, key string, req *pb.SendMessageRequest, msg *pb.Message) error {
+	switch content := req.GetContent().(type) {
+	case *pb.SendMessageRequest_Text:
+		err := validateText(content.Text)
+		if err != nil {
+			return err
+		}
+
+		msg.Content = &pb.Message_Text{Text: content.Text}
+	case *pb.SendMessageRequest_Attachment:
+		caption := content.Attachment.GetCaption()
+		if len(caption) > maxCaptionLength || !utf8.ValidString(caption) {
+			return status.Errorf(codes.InvalidArgument, "caption must be valid UTF-8 of at most %d bytes", maxCaptionLength)
+		}
+
+		attachments, err := s.messageAttachments(ctx, key, msg.GetSender(), []string{content.Attachment.GetAttachment().GetId()})
+		if err != nil {
+			return err
+		}
+
+		msg.Content = &pb.Message_Attachment{
+			Attachment: &pb.AttachmentContent{
+				Attachment: attachments[0],
+				Caption:    caption,
+			},
+		}
+	case *pb.SendMessageRequest_Card:
+		err := validateCard(content.Card)
+		if err != nil {
+			return err
+		}
+
+		msg.Content = &pb.Message_Card{Card: content.Card}
+	default:
+		text := &pb.TextContent{
+			Text: req.GetMessage(),
+		}
+
+		if text.GetText() == "" && len(msg.GetAttachments()) > 0 {
+			break
+		}
+
+		err := validateText(text)
+		if err != nil {
+			return err
+		}
+
+		msg.Content = &pb.Message_Text{Text: text}
+	}
+
+	msg.Message = renderContent(msg)
+
+	return nil
+}
+
+// postSystemEvent posts a system message about user to group, failures are only logged
+func (s *ChatService) postSystemEvent(ctx context.Context, group string, eventType pb.SystemEventType, user string) {
+	// the group is gone once its last member left
+	channel, ok := s.hub.Channel(group)
+	if !ok {
+		return
+	}
+
+	msg := &pb.Message{
+		Channel: &pb.Channel{
+			Type: channel.Type,
+			Name: channel.Name,
+		},
+		Sender: user,
+		Time:   timestamppb.New(time.Now()),
+		Id:     newID(),
+		Content: &pb.Message_System{
+			System: &pb.SystemEvent{
+				Type: eventType,
+				User: user,
+			},
+		},
+	}
+
+	msg.Message = renderContent(msg)
+
+	err := s.post(ctx, channel, store.ChannelKey(msg.Channel, user), msg)
+	if err != nil {
+		log.Printf("failed to post system event: %v", err)
+	}
+}
+
+// renderContent returns the plain text of the content of msg shown by clients not knowing the content
+func renderContent(msg *pb.Message) string {
+	switch content := msg.GetContent().(type) {
+	case *pb.Message_Text:
+		return content.Text.GetText()
+	case *pb.Message_System:
+		user := content.System.GetUser()
+		switch content.System.GetType() {
+		case pb.SystemEventType_GROUP_CREATED:
+			return user + " created the group"
+		case pb.SystemEventType_MEMBER_JOINED:
+			return user + " joined the group"
+		case pb.SystemEventType_MEMBER_LEFT:
+			return user + " left the group"
+		}
+	case *pb.Message_Attachment:
+		text := fmt.Sprintf("[%s]", content.Attachment.GetAttachment().GetName())
+		if caption := content.Attachment.GetCaption(); caption != "" {
+			text += " " + caption
+		}
+
+		return text
+	case *pb.Message_Card:
+		lines := []string{content.Card.GetTitle()}
+		if text := content.Card.GetText(); text != "" {
+			lines = append(lines, text)
+		}
+
+		for _, action := range content.Card.GetActions() {
+			lines = append(lines, action.GetLabel()+": "+action.GetUrl())
+		}
+
+		return strings.Join(lines, "\n")
+	}
+
+	return msg.GetMessage()
+}
+
+func validateText(text *pb.TextContent) error {
+	if text.GetText() == "" {
+		return status.Error(codes.InvalidArgument, "message is required")
+	}
+
+	if len(text.GetText()) > maxTextLength || !utf8.ValidString(text.GetText()) {
+		return status.Errorf(codes.InvalidArgument, "message must be valid UTF-8 of at most %d bytes", maxTextLength)
+	}
+
+	if _, ok := pb.Markup_name[int32(text.GetMarkup())]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown markup %d", text.GetMarkup())
+	}
+
+	return nil
+}
+
+func validateCard(card *pb.Card) error {
+	if card.GetTitle() == "" || len(card.GetTitle()) > maxCardTitleLength || !utf8.ValidString(card.GetTitle()) {
+		return status.Errorf(codes.InvalidArgument, "card title is required and must be valid UTF-8 of at most %d bytes", maxCardTitleLength)
+	}
+
+	if len(card.GetText()) > maxTextLength || !utf8.ValidString(card.GetText()) {
+		return status.Errorf(codes.InvalidArgument, "card text must be valid UTF-8 of at most %d bytes", maxTextLength)
+	}
+
+	if card.GetImageUrl() != "" && !validURL(card.GetImageUrl()) {
+		return status.Errorf(codes.InvalidArgument, "invalid card image url %q", card.GetImageUrl())
+	}
+
+	if len(card.GetActions()) > maxCardActions {
+		return status.Errorf(codes.InvalidArgument, "a card can have at most %d actions", maxCardActions)
+	}
+
+	for _, action := range card.GetActions() {
+		if action.GetLabel() == "" || !utf8.ValidString(action.GetLabel()) {
+			return status.Error(codes.InvalidArgument, "card action label is required")
+		}
+
+		if !validURL(action.GetUrl()) {
+			return status.Errorf(codes.InvalidArgument, "invalid card action url %q", action.GetUrl())
+		}
+	}
+
+	return nil
+}
+
+// validURL reports whether raw is an absolute http or https url
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func TestSendMessageRendersContent(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	connectAs(t, s, "bob")
+
+	tests := []struct {
+		req  *pb.SendMessageRequest
+		want string
+	}{
+		{
+			req:  &pb.SendMessageRequest{Message: "plain"},
+			want: "plain",
+		},
+		{
+			req: &pb.SendMessageRequest{Content: &pb.SendMessageRequest_Text{
+				Text: &pb.TextContent{Text: "*bold*", Markup: pb.Markup_MARKDOWN},
+			}},
+			want: "*bold*",
+		},
+		{
+			req: &pb.SendMessageRequest{Content: &pb.SendMessageRequest_Card{
+				Card: &pb.Card{
+					Title:   "Release",
+					Text:    "v1 is out",
+					Actions: []*pb.CardAction{{Label: "Notes", Url: "https://example.com/v1"}},
+				},
+			}},
+			want: "Release\nv1 is out\nNotes: https://example.com/v1",
+		},
+	}
+
+	for _, test := range tests {
+		test.req.Receiver = "bob"
+
+		msg, err := s.sendMessage(alice, "alice", test.req)
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+
+		if msg.GetMessage() != test.want || msg.GetContent() == nil {
+			t.Errorf("message = %v, want content rendered as %q", msg, test.want)
+		}
+	}
+}
+
+func TestSendMessageValidatesContent(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	connectAs(t, s, "bob")
+
+	card := func(card *pb.Card) *pb.SendMessageRequest {
+		return &pb.SendMessageRequest{Content: &pb.SendMessageRequest_Card{Card: card}}
+	}
+
+	actions := make([]*pb.CardAction, maxCardActions+1)
+	for i := range actions {
+		actions[i] = &pb.CardAction{Label: "open", Url: "https://example.com"}
+	}
+
+	tests := map[string]*pb.SendMessageRequest{
+		"empty":          {},
+		"too long":       {Message: strings.Repeat("a", maxTextLength+1)},
+		"invalid UTF-8":  {Message: "\xff"},
+		"unknown markup": {Content: &pb.SendMessageRequest_Text{Text: &pb.TextContent{Text: "hi", Markup: 7}}},
+		"untitled card":  card(&pb.Card{Text: "text"}),
+		"image url":      card(&pb.Card{Title: "t", ImageUrl: "javascript:alert(1)"}),
+		"action url":     card(&pb.Card{Title: "t", Actions: []*pb.CardAction{{Label: "open", Url: "/relative"}}}),
+		"many actions":   card(&pb.Card{Title: "t", Actions: actions}),
+		"long caption": {Content: &pb.SendMessageRequest_Attachment{
+			Attachment: &pb.AttachmentContent{Caption: strings.Repeat("a", maxCaptionLength+1)},
+		}},
+	}
+
+	for name, req := range tests {
+		req.Receiver = "bob"
+
+		_, err := s.sendMessage(alice, "alice", req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: sendMessage = %v, want InvalidArgument", name, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func (s *ChatService) EditMessage(ctx context.Context, req *pb.EditMessageRequest) (*pb.Message, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateText(&pb.TextContent{Text: req.GetMessage()})
+	if err != nil {
+		return nil, err
+	}
+
+	msg, _, err := s.accessibleMessage(ctx, user, req.GetMessageId())
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.conversationLocks.lock(store.ChannelKey(msg.GetChannel(), msg.GetSender())).Unlock()
+
+	msg, err = s.messages.Update(ctx, msg.GetId(), func(msg *pb.Message) error {
+		if msg.GetSender() != user {
+			return status.Error(codes.PermissionDenied, "only the sender can edit a message")
+		}
+
+		if msg.GetDeleted() {
+			return status.Errorf(codes.FailedPrecondition, "message %s is deleted", msg.GetId())
+		}
+
+		// messages stored before structured content have no content and are text
+		text := msg.GetText()
+		if text == nil && msg.GetContent() != nil {
+			return status.Errorf(codes.FailedPrecondition, "message %s is not a text message", msg.GetId())
+		}
+
+		written := msg.GetEditTime()
+		if written == nil {
+			written = msg.GetTime()
+		}
+
+		msg.Revisions = append(msg.Revisions, &pb.MessageRevision{
+			Message: msg.GetMessage(),
+			Time:    written,
+		})
+		msg.Content = &pb.Message_Text{
+			Text: &pb.TextContent{
+				Text:   req.GetMessage(),
+				Markup: text.GetMarkup(),
+			},
+		}
+		msg.Message = req.GetMessage()
+		msg.EditTime = timestamppb.New(time.Now())
+
+		return nil
+	})
+	if err != nil {
+		return nil, updateError(err, "failed to edit message")
+	}
+
+	s.broadcastUpdate(pb.UpdateType_EDITED, msg)
+
+	return msg, nil
+}
+
+func (s *ChatService) DeleteMessage(ctx context.Context, req *pb.DeleteMessageRequest) (*emptypb.Empty, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, channel, err := s.accessibleMessage(ctx, user, req.GetMessageId())
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.conversationLocks.lock(store.ChannelKey(msg.GetChannel(), msg.GetSender())).Unlock()
+
+	msg, err = s.messages.Update(ctx, msg.GetId(), func(msg *pb.Message) error {
+		if msg.GetSender() != user && !channel.IsAdmin(user) {
+			return status.Error(codes.PermissionDenied, "only the sender or a group admin can delete a message")
+		}
+
+		if msg.GetDeleted() {
+			return status.Errorf(codes.FailedPrecondition, "message %s is already deleted", msg.GetId())
+		}
+
+		// the message keeps its place in the channel so sequence numbers stay without gaps
+		msg.Message = ""
+		msg.Content = nil
+		msg.Attachments = nil
+		msg.Revisions = nil
+		msg.Reactions = nil
+		msg.Deleted = true
+		msg.EditTime = timestamppb.New(time.Now())
+
+		return nil
+	})
+	if err != nil {
+		return nil, updateError(err, "failed to delete message")
+	}
+
+	// a deleted reply no longer counts for its thread
+	if msg.GetParentId() != "" {
+		s.countReply(ctx, msg.GetParentId(), -1)
+	}
+
+	s.broadcastUpdate(pb.UpdateType_DELETED, msg)
+
+	return &emptypb.Empty{}, nil
+}
+
+// accessibleMessage returns the message id after checking user takes part in its channel.
+// The returned channel is the group of a group message and empty for direct messages.
+func (s *ChatService) accessibleMessage(ctx context.Context, user, id string) (*pb.Message, hub.Channel, error) {
+	msg, err := s.messages.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, hub.Channel{}, status.Errorf(codes.NotFound, "message %s not found", id)
+	}
+
+	if err != nil {
+		log.Printf("failed to get message: %v", err)
+		return nil, hub.Channel{}, status.Error(codes.Internal, "failed to get message")
+	}
+
+	channel, err := s.checkParticipant(user, msg.GetSender(), msg.GetChannel(), "message "+id)
+	if err != nil {
+		return nil, hub.Channel{}, err
+	}
+
+	return msg, channel, nil
+}
+
+// checkParticipant checks user takes part in the conversation of sender in channel,
+// what names the requested item in the NotFound error of a conversation user does not see.
+// The returned channel is the group of a group conversation and empty for direct conversations.
+func (s *ChatService) checkParticipant(user, sender string, pbChannel *pb.Channel, what string) (hub.Channel, error) {
+	if pbChannel.GetType() != pb.ChannelType_GROUP {
+		if user != sender && user != pbChannel.GetName() {
+			return hub.Channel{}, status.Errorf(codes.NotFound, "%s not found", what)
+		}
+
+		return hub.Channel{}, nil
+	}
+
+	channel, ok := s.hub.Channel(pbChannel.GetName())
+	if !ok || channel.Type != pb.ChannelType_GROUP {
+		return hub.Channel{}, status.Errorf(codes.NotFound, "%s not found", what)
+	}
+
+	if !channel.HasUser(user) {
+		return hub.Channel{}, status.Errorf(codes.PermissionDenied, "not a member of %s", channel.Name)
+	}
+
+	return channel, nil
+}
+
+// broadcastUpdate sends the change of msg to the participants of its channel
+func (s *ChatService) broadcastUpdate(updateType pb.UpdateType, msg *pb.Message) {
+	event := &pb.ServerEvent{
+		Event: &pb.ServerEvent_Update{
+			Update: &pb.MessageUpdate{
+				Type:    updateType,
+				Message: msg,
+			},
+		},
+	}
+
+	for _, user := range s.participants(msg) {
+		// updates are ephemeral, offline users see them in the history
+		_ = s.hub.Deliver(user, event)
+	}
+}
+
+// participants returns the users taking part in the channel of msg
+func (s *ChatService) participants(msg *pb.Message) []string {
+	if msg.GetChannel().GetType() != pb.ChannelType_GROUP {
+		if msg.GetSender() == msg.GetChannel().GetName() {
+			return []string{msg.GetSender()}
+		}
+
+		return []string{msg.GetSender(), msg.GetChannel().GetName()}
+	}
+
+	channel, ok := s.hub.Channel(msg.GetChannel().GetName())
+	if !ok {
+		return nil
+	}
+
+	return channel.Users
+}
+
+// updateError converts an error of updating a message to a status error
+func updateError(err error, message string) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return status.Error(codes.NotFound, "message not found")
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	log.Printf("%s: %v", message, err)
+
+	return status.Error(codes.Internal, message)
+}
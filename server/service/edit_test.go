@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// updateOf waits for a message update on stream, it returns nil when none arrives in time
+func updateOf(stream *connectStream, wait time.Duration) *pb.MessageUpdate {
+	timeout := time.After(wait)
+	for {
+		select {
+		case res := <-stream.sent:
+			if update := res.GetEvent().GetUpdate(); update != nil {
+				return update
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+func TestEditMessageBySenderOnly(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	bob := openConnect(t, s, "bob", &pb.ConnectRequest{Events: true})
+	bobCtx := auth.NewContext(context.Background(), auth.Principal{Name: "bob"})
+	carol := connectAs(t, s, "carol")
+
+	msg, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	_, err = s.EditMessage(bobCtx, &pb.EditMessageRequest{MessageId: msg.GetId(), Message: "forged"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("EditMessage by the receiver = %v, want PermissionDenied", err)
+	}
+
+	_, err = s.EditMessage(carol, &pb.EditMessageRequest{MessageId: msg.GetId(), Message: "forged"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("EditMessage by an outsider = %v, want NotFound", err)
+	}
+
+	edited, err := s.EditMessage(alice, &pb.EditMessageRequest{MessageId: msg.GetId(), Message: "hi"})
+	if err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+
+	if edited.GetMessage() != "hi" || edited.GetEditTime() == nil || edited.GetSeq() != msg.GetSeq() {
+		t.Fatalf("edited message = %v, want hi with edit time at the same seq", edited)
+	}
+
+	if revisions := edited.GetRevisions(); len(revisions) != 1 || revisions[0].GetMessage() != "hello" {
+		t.Fatalf("revisions = %v, want the original text", revisions)
+	}
+
+	update := updateOf(bob, time.Second)
+	if update.GetType() != pb.UpdateType_EDITED || update.GetMessage().GetMessage() != "hi" {
+		t.Fatalf("bob got update %v, want the edit", update)
+	}
+}
+
+func TestDeleteMessageBySenderOrAdmin(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	bob := connectAs(t, s, "bob")
+	carol := connectAs(t, s, "carol")
+
+	_, err := s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	for _, ctx := range []context.Context{bob, carol} {
+		_, err = s.JoinGroupChat(ctx, &pb.JoinGroupChatRequest{ChannelName: "team"})
+		if err != nil {
+			t.Fatalf("JoinGroupChat: %v", err)
+		}
+	}
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		msg, err := s.sendMessage(bob, "bob", &pb.SendMessageRequest{Receiver: "team", Message: "hello"})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+
+		ids = append(ids, msg.GetId())
+	}
+
+	_, err = s.DeleteMessage(carol, &pb.DeleteMessageRequest{MessageId: ids[0]})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("DeleteMessage by another member = %v, want PermissionDenied", err)
+	}
+
+	// the group admin deletes messages of others, the sender deletes own ones
+	_, err = s.DeleteMessage(alice, &pb.DeleteMessageRequest{MessageId: ids[0]})
+	if err != nil {
+		t.Fatalf("DeleteMessage by the admin: %v", err)
+	}
+
+	_, err = s.DeleteMessage(bob, &pb.DeleteMessageRequest{MessageId: ids[1]})
+	if err != nil {
+		t.Fatalf("DeleteMessage by the sender: %v", err)
+	}
+
+	_, err = s.DeleteMessage(bob, &pb.DeleteMessageRequest{MessageId: ids[1]})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("second DeleteMessage = %v, want FailedPrecondition", err)
+	}
+
+	_, err = s.EditMessage(bob, &pb.EditMessageRequest{MessageId: ids[1], Message: "back"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("EditMessage of a deleted message = %v, want FailedPrecondition", err)
+	}
+
+	for _, msg := range history(t, s, carol, "team") {
+		if msg.GetSender() == "bob" && msg.GetSystem() == nil && (!msg.GetDeleted() || msg.GetMessage() != "") {
+			t.Fatalf("history has %v, want deleted messages without text", msg)
+		}
+	}
+}
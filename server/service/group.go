@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// lockGroup serializes changing the membership of group name with persisting it,
+// so that the stored membership is always the latest one
+func (s *ChatService) lockGroup(name string) func() {
+	return s.groupLocks.lock(name).Unlock
+}
+
+// saveGroup persists the current membership of group name.
+// A group which no longer exists is removed together with its conversation, so that a new
+// group of the same name does not inherit its history. It must be called with the group locked.
+func (s *ChatService) saveGroup(ctx context.Context, name string) {
+	channel, ok := s.hub.Channel(name)
+	if ok && channel.Type == pb.ChannelType_GROUP {
+		err := s.groups.PutGroup(ctx, store.Group{
+			Name:   channel.Name,
+			Users:  channel.Users,
+			Admins: channel.Admins,
+		})
+		if err != nil {
+			log.Printf("failed to store group %s: %v", name, err)
+		}
+
+		return
+	}
+
+	err := s.groups.DeleteGroup(ctx, name)
+	if err != nil {
+		log.Printf("failed to delete group %s: %v", name, err)
+	}
+
+	key := store.ChannelKey(&pb.Channel{Type: pb.ChannelType_GROUP, Name: name}, "")
+	defer s.conversationLocks.lock(key).Unlock()
+
+	err = s.messages.Delete(ctx, key, time.Time{}, time.Time{})
+	if err != nil {
+		log.Printf("failed to delete conversation of group %s: %v", name, err)
+	}
+}
+
+// userGroups returns the names of the groups user is a member of
+func (s *ChatService) userGroups(user string) []string {
+	var groups []string
+	for _, channel := range s.hub.Channels() {
+		if channel.Type == pb.ChannelType_GROUP && channel.HasUser(user) {
+			groups = append(groups, channel.Name)
+		}
+	}
+
+	return groups
+}
+
+// Restore registers the accounts and the persisted groups of st in h, it is called once on startup.
+// Names taken by both a user and a group are logged and skipped.
+func Restore(ctx context.Context, h *hub.Hub, st store.Store) error {
+	accounts, err := st.Accounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range accounts {
+		_, err = h.AddUser(a.Username)
+		if err != nil {
+			log.Printf("failed to restore user %s: %v", a.Username, err)
+		}
+	}
+
+	groups, err := st.Groups(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		err = h.RestoreGroup(g.Name, g.Users, g.Admins)
+		if err != nil {
+			log.Printf("failed to restore group %s: %v", g.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// connectAs connects user to s and returns a context authenticated as user
+func connectAs(t *testing.T, s *ChatService, user string) context.Context {
+	t.Helper()
+
+	session, err := s.connect(user)
+	if err != nil {
+		t.Fatalf("connect %s: %v", user, err)
+	}
+
+	t.Cleanup(func() { s.disconnect(session) })
+
+	return auth.NewContext(context.Background(), auth.Principal{Name: user})
+}
+
+func history(t *testing.T, s *ChatService, ctx context.Context, group string) []*pb.Message {
+	t.Helper()
+
+	res, err := s.GetHistory(ctx, &pb.GetHistoryRequest{
+		Channel: &pb.Channel{Type: pb.ChannelType_GROUP, Name: group},
+	})
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+
+	return res.GetMessages()
+}
+
+func TestGroupsSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.db")
+
+	st, err := store.NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+
+	s := NewChatService(hub.New(hub.Config{}), st, Config{})
+	alice := connectAs(t, s, "alice")
+	bob := connectAs(t, s, "bob")
+
+	_, err = s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	_, err = s.JoinGroupChat(bob, &pb.JoinGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("JoinGroupChat: %v", err)
+	}
+
+	_, err = s.sendMessage(bob, "bob", &pb.SendMessageRequest{Receiver: "team", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	err = st.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	st, err = store.NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+
+	defer st.Close()
+
+	h := hub.New(hub.Config{})
+	err = Restore(context.Background(), h, st)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	channel, ok := h.Channel("team")
+	if !ok || !channel.HasUser("alice") || !channel.HasUser("bob") || !channel.IsAdmin("alice") {
+		t.Fatalf("restored group = %+v, want alice as admin and bob", channel)
+	}
+
+	s = NewChatService(h, st, Config{})
+	messages := history(t, s, connectAs(t, s, "bob"), "team")
+	if len(messages) == 0 || messages[len(messages)-1].GetMessage() != "hello" {
+		t.Fatalf("history after restart = %v, want the message of bob", messages)
+	}
+}
+
+func TestRecreatedGroupDoesNotInheritHistory(t *testing.T) {
+	st := store.NewMemoryStore()
+	s := NewChatService(hub.New(hub.Config{}), st, Config{})
+	alice := connectAs(t, s, "alice")
+	mallory := connectAs(t, s, "mallory")
+
+	_, err := s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	_, err = s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "team", Message: "secret"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	_, err = s.LeaveGroupChat(alice, &pb.LeaveGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("LeaveGroupChat: %v", err)
+	}
+
+	groups, err := st.Groups(context.Background())
+	if err != nil || len(groups) != 0 {
+		t.Fatalf("stored groups = %v, %v, want none", groups, err)
+	}
+
+	_, err = s.CreateGroupChat(mallory, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	for _, msg := range history(t, s, mallory, "team") {
+		if msg.GetSender() == "alice" {
+			t.Fatalf("new group sees message %v of the deleted group", msg)
+		}
+	}
+}
+
+func TestGroupLifecycle(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	bob := connectAs(t, s, "bob")
+
+	_, err := s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	_, err = s.CreateGroupChat(bob, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("CreateGroupChat of an existing group = %v, want AlreadyExists", err)
+	}
+
+	_, err = s.JoinGroupChat(bob, &pb.JoinGroupChatRequest{ChannelName: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("JoinGroupChat of an unknown group = %v, want NotFound", err)
+	}
+
+	_, err = s.JoinGroupChat(bob, &pb.JoinGroupChatRequest{ChannelName: "alice"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("JoinGroupChat of a user = %v, want FailedPrecondition", err)
+	}
+
+	_, err = s.sendMessage(bob, "bob", &pb.SendMessageRequest{Receiver: "team", Message: "hello"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("send to a group bob is not a member of = %v, want PermissionDenied", err)
+	}
+
+	_, err = s.JoinGroupChat(bob, &pb.JoinGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("JoinGroupChat: %v", err)
+	}
+
+	_, err = s.JoinGroupChat(bob, &pb.JoinGroupChatRequest{ChannelName: "team"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("second JoinGroupChat = %v, want AlreadyExists", err)
+	}
+
+	_, err = s.sendMessage(bob, "bob", &pb.SendMessageRequest{Receiver: "team", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	var events []pb.SystemEventType
+	for _, msg := range history(t, s, alice, "team") {
+		if system := msg.GetSystem(); system != nil {
+			events = append(events, system.GetType())
+		}
+	}
+
+	if len(events) != 2 || events[0] != pb.SystemEventType_GROUP_CREATED || events[1] != pb.SystemEventType_MEMBER_JOINED {
+		t.Fatalf("system events = %v, want created and joined", events)
+	}
+
+	_, err = s.LeaveGroupChat(alice, &pb.LeaveGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("LeaveGroupChat: %v", err)
+	}
+
+	_, err = s.LeaveGroupChat(alice, &pb.LeaveGroupChatRequest{ChannelName: "team"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("second LeaveGroupChat = %v, want FailedPrecondition", err)
+	}
+
+	// the last admin left, the remaining member takes over
+	channel, ok := s.hub.Channel("team")
+	if !ok || channel.HasUser("alice") || !channel.IsAdmin("bob") {
+		t.Fatalf("group after alice left = %+v, want bob as admin", channel)
+	}
+
+	_, err = s.LeaveGroupChat(bob, &pb.LeaveGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("LeaveGroupChat: %v", err)
+	}
+
+	if _, ok := s.hub.Channel("team"); ok {
+		t.Fatal("group still exists after its last member left")
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 200
+)
+
+func (s *ChatService) GetHistory(ctx context.Context, req *pb.GetHistoryRequest) (*pb.GetHistoryResponse, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.conversationKey(user, req.GetChannel())
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := decodeCursor(req.GetCursor())
+	if err != nil {
+		return nil, err
+	}
+
+	limit := pageSize(req.GetPageSize())
+
+	messages, err := s.messages.Last(ctx, key, before, limit)
+	if err != nil {
+		log.Printf("failed to fetch history: %v", err)
+		return nil, status.Error(codes.Internal, "failed to fetch history")
+	}
+
+	res := &pb.GetHistoryResponse{
+		Messages: messages,
+	}
+
+	if len(messages) == limit && messages[0].GetSeq() > 1 {
+		res.NextCursor = encodeCursor(messages[0].GetSeq())
+	}
+
+	return res, nil
+}
+
+// conversationKey returns the storage key of channel after checking user takes part in it
+func (s *ChatService) conversationKey(user string, pbChannel *pb.Channel) (string, error) {
+	channel, ok := s.hub.Channel(pbChannel.GetName())
+	if !ok || channel.Type != pbChannel.GetType() {
+		return "", status.Errorf(codes.NotFound, "channel %s not found", pbChannel.GetName())
+	}
+
+	if channel.Type == pb.ChannelType_GROUP && !channel.HasUser(user) {
+		return "", status.Errorf(codes.PermissionDenied, "not a member of %s", channel.Name)
+	}
+
+	// user always takes part in the direct conversation with the channel user
+	return store.ChannelKey(pbChannel, user), nil
+}
+
+// pageSize returns the requested page size limited to maxHistoryPageSize, zero means the default size
+func pageSize(requested int32) int {
+	if requested <= 0 {
+		return defaultHistoryPageSize
+	}
+
+	if requested > maxHistoryPageSize {
+		return maxHistoryPageSize
+	}
+
+	return int(requested)
+}
+
+// encodeCursor returns an opaque cursor pointing at the message seq
+func encodeCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || seq <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func TestGetHistoryPagesBackwards(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	bob := connectAs(t, s, "bob")
+
+	for i := 1; i <= 5; i++ {
+		_, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+	}
+
+	var pages [][]string
+	req := &pb.GetHistoryRequest{Channel: &pb.Channel{Type: pb.ChannelType_USER, Name: "alice"}, PageSize: 2}
+	for {
+		res, err := s.GetHistory(bob, req)
+		if err != nil {
+			t.Fatalf("GetHistory: %v", err)
+		}
+
+		var page []string
+		for _, msg := range res.GetMessages() {
+			page = append(page, msg.GetMessage())
+		}
+
+		pages = append(pages, page)
+
+		if res.GetNextCursor() == "" {
+			break
+		}
+
+		req.Cursor = res.GetNextCursor()
+	}
+
+	if got := fmt.Sprint(pages); got != "[[4 5] [2 3] [1]]" {
+		t.Fatalf("pages = %s, want [[4 5] [2 3] [1]]", got)
+	}
+
+	req.Cursor = "not a cursor"
+	_, err := s.GetHistory(bob, req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("GetHistory with an invalid cursor = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGetHistoryChecksAccess(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	connectAs(t, s, "bob")
+	mallory := connectAs(t, s, "mallory")
+
+	_, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "secret"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	_, err = s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	_, err = s.GetHistory(mallory, &pb.GetHistoryRequest{Channel: &pb.Channel{Type: pb.ChannelType_GROUP, Name: "team"}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("GetHistory of a group of others = %v, want PermissionDenied", err)
+	}
+
+	_, err = s.GetHistory(mallory, &pb.GetHistoryRequest{Channel: &pb.Channel{Type: pb.ChannelType_GROUP, Name: "alice"}})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("GetHistory of a user as group = %v, want NotFound", err)
+	}
+
+	// the direct channel of mallory and bob is another conversation
+	res, err := s.GetHistory(mallory, &pb.GetHistoryRequest{Channel: &pb.Channel{Type: pb.ChannelType_USER, Name: "bob"}})
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+
+	if len(res.GetMessages()) != 0 {
+		t.Fatalf("mallory sees %v of the conversation of alice and bob", res.GetMessages())
+	}
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+const lockStripes = 64
+
+// stripedMutex hands out one of a fixed set of mutexes per key,
+// so that work on the same key is serialized without a lock per key
+type stripedMutex struct {
+	stripes [lockStripes]sync.Mutex
+}
+
+// lock locks the mutex of key and returns it for unlocking
+func (m *stripedMutex) lock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	mu := &m.stripes[h.Sum32()%lockStripes]
+	mu.Lock()
+
+	return mu
+}
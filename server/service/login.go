@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+const (
+	minPasswordLength = 8
+	// maxPasswordLength is the number of bytes bcrypt takes into account
+	maxPasswordLength = 72
+)
+
+func (s *ChatService) Register(ctx context.Context, req *pb.RegisterRequest) (*emptypb.Empty, error) {
+	err := auth.ValidateUsername(req.GetUsername())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = validatePassword(req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	// users and groups share one namespace, adding the user to the hub reserves the name
+	// before the account is written, so that a concurrent group of the same name fails.
+	// The user can receive messages before connecting for the first time.
+	added, err := s.hub.AddUser(req.GetUsername())
+	if err != nil {
+		return nil, status.Errorf(codes.AlreadyExists, "name %s is taken", req.GetUsername())
+	}
+
+	err = s.createAccount(ctx, req)
+	if err != nil && added {
+		s.hub.RemoveUser(req.GetUsername())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ChatService) createAccount(ctx context.Context, req *pb.RegisterRequest) error {
+	hash, err := auth.HashPassword(req.GetPassword())
+	if err != nil {
+		log.Printf("failed to hash password: %v", err)
+		return status.Error(codes.Internal, "failed to register")
+	}
+
+	err = s.accounts.CreateAccount(ctx, store.Account{
+		Username:     req.GetUsername(),
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	})
+	if errors.Is(err, store.ErrExists) {
+		return status.Errorf(codes.AlreadyExists, "name %s is taken", req.GetUsername())
+	}
+
+	if err != nil {
+		log.Printf("failed to create account: %v", err)
+		return status.Error(codes.Internal, "failed to register")
+	}
+
+	return nil
+}
+
+func (s *ChatService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	account, err := s.checkPassword(ctx, req.GetUsername(), req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueToken(account)
+}
+
+func (s *ChatService) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.LoginResponse, error) {
+	user := principalName(ctx)
+
+	account, err := s.checkPassword(ctx, user, req.GetOldPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	err = validatePassword(req.GetNewPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	account.PasswordHash, err = auth.HashPassword(req.GetNewPassword())
+	if err != nil {
+		log.Printf("failed to hash password: %v", err)
+		return nil, status.Error(codes.Internal, "failed to change password")
+	}
+
+	// all tokens issued so far belong to the previous generation
+	account.TokenGeneration++
+
+	err = s.accounts.UpdateAccount(ctx, account)
+	if err != nil {
+		log.Printf("failed to update account: %v", err)
+		return nil, status.Error(codes.Internal, "failed to change password")
+	}
+
+	return s.issueToken(account)
+}
+
+func (s *ChatService) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*emptypb.Empty, error) {
+	user := principalName(ctx)
+
+	_, err := s.checkPassword(ctx, user, req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.accounts.DeleteAccount(ctx, user)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("failed to delete account: %v", err)
+		return nil, status.Error(codes.Internal, "failed to delete account")
+	}
+
+	member := s.userGroups(user)
+
+	groups := s.hub.DeleteUser(user, status.Error(codes.Unauthenticated, "account deleted"))
+	for _, group := range member {
+		unlock := s.lockGroup(group)
+		s.saveGroup(ctx, group)
+		unlock()
+	}
+
+	for _, group := range groups {
+		s.postSystemEvent(ctx, group, pb.SystemEventType_MEMBER_LEFT, user)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// checkPassword returns the account of user if password matches it
+func (s *ChatService) checkPassword(ctx context.Context, user, password string) (store.Account, error) {
+	account, err := s.accounts.Account(ctx, user)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("failed to get account: %v", err)
+		return store.Account{}, status.Error(codes.Internal, "failed to check password")
+	}
+
+	// unknown users are checked against no hash so that they are not told apart by timing
+	if !auth.CheckPassword(account.PasswordHash, password) {
+		return store.Account{}, status.Error(codes.Unauthenticated, "invalid username or password")
+	}
+
+	return account, nil
+}
+
+func (s *ChatService) issueToken(account store.Account) (*pb.LoginResponse, error) {
+	token, expiresAt, err := s.tokens.Issue(account.Username, account.TokenGeneration)
+	if err != nil {
+		log.Printf("failed to issue token: %v", err)
+		return nil, status.Error(codes.Internal, "failed to issue token")
+	}
+
+	return &pb.LoginResponse{
+		Token:      token,
+		ExpireTime: timestamppb.New(expiresAt),
+	}, nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return status.Errorf(codes.InvalidArgument, "password must be %d to %d bytes", minPasswordLength, maxPasswordLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func newLoginService(t *testing.T) (*ChatService, *auth.Tokens) {
+	t.Helper()
+
+	tokens := auth.NewTokens([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{Tokens: tokens})
+
+	_, err := s.Register(context.Background(), &pb.RegisterRequest{Username: "bob", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	return s, tokens
+}
+
+func TestLoginRequiresPassword(t *testing.T) {
+	s, _ := newLoginService(t)
+
+	for _, req := range []*pb.LoginRequest{
+		{Username: "bob"},
+		{Username: "bob", Password: "wrong password"},
+		{Username: "nobody", Password: "correct horse"},
+	} {
+		res, err := s.Login(context.Background(), req)
+		if status.Code(err) != codes.Unauthenticated || res.GetToken() != "" {
+			t.Errorf("Login(%q, %q) = %v, %v, want Unauthenticated without a token", req.GetUsername(), req.GetPassword(), res, err)
+		}
+	}
+}
+
+func TestLoginIssuesTokenOfUser(t *testing.T) {
+	s, tokens := newLoginService(t)
+
+	res, err := s.Login(context.Background(), &pb.LoginRequest{Username: "bob", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	claims, err := tokens.Verify(res.GetToken())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	if claims.Subject != "bob" {
+		t.Fatalf("token subject = %q, want bob", claims.Subject)
+	}
+}
+
+func TestDeletedAccountCanNotBeRegisteredAgain(t *testing.T) {
+	s, _ := newLoginService(t)
+	bob := auth.NewContext(context.Background(), auth.Principal{Name: "bob"})
+
+	_, err := s.DeleteAccount(bob, &pb.DeleteAccountRequest{Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+
+	_, err = s.Register(context.Background(), &pb.RegisterRequest{Username: "bob", Password: "another password"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("Register of deleted bob: %v, want AlreadyExists", err)
+	}
+
+	// the failed registration does not leave a user behind in the hub
+	if _, ok := s.hub.Channel("bob"); ok {
+		t.Fatal("failed registration left bob in the hub")
+	}
+}
+
+func TestRegisteredUserReceivesMessagesBeforeConnecting(t *testing.T) {
+	s, _ := newLoginService(t)
+	alice := connectAs(t, s, "alice")
+
+	_, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "welcome"})
+	if err != nil {
+		t.Fatalf("sendMessage to registered bob: %v", err)
+	}
+
+	res, err := s.ListChannels(alice, nil)
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+
+	found := false
+	for _, channel := range res.GetChannels() {
+		found = found || channel.GetName() == "bob"
+	}
+
+	if !found {
+		t.Fatalf("channels %v do not include bob", res.GetChannels())
+	}
+
+	session, err := s.connect("bob")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	defer s.disconnect(session)
+
+	backlog := session.Backlog()
+	if len(backlog) != 1 || backlog[0].GetMessage().GetMessage() != "welcome" {
+		t.Fatalf("backlog of bob = %v, want the welcome message", backlog)
+	}
+}
+
+func TestRestoreAddsAccounts(t *testing.T) {
+	st := store.NewMemoryStore()
+
+	err := st.CreateAccount(context.Background(), store.Account{Username: "bob"})
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+
+	h := hub.New(hub.Config{})
+
+	err = Restore(context.Background(), h, st)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	channel, ok := h.Channel("bob")
+	if !ok || channel.Type != pb.ChannelType_USER {
+		t.Fatalf("bob is not a known user after restore")
+	}
+}
+
+func TestRegisterAndCreateGroupDoNotShareName(t *testing.T) {
+	s, _ := newLoginService(t)
+	alice := connectAs(t, s, "alice")
+
+	for _, name := range []string{"team1", "team2", "team3"} {
+		var wg sync.WaitGroup
+		var registerErr, groupErr error
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, registerErr = s.Register(context.Background(), &pb.RegisterRequest{Username: name, Password: "correct horse"})
+		}()
+		go func() {
+			defer wg.Done()
+			_, groupErr = s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: name})
+		}()
+		wg.Wait()
+
+		if (registerErr == nil) == (groupErr == nil) {
+			t.Fatalf("%s: Register = %v, CreateGroupChat = %v, want exactly one to succeed", name, registerErr, groupErr)
+		}
+
+		channel, _ := s.hub.Channel(name)
+		_, accountErr := s.accounts.Account(context.Background(), name)
+		if registerErr == nil && (channel.Type != pb.ChannelType_USER || accountErr != nil) {
+			t.Fatalf("%s: registered name is %v with account error %v", name, channel.Type, accountErr)
+		}
+
+		if groupErr == nil && (channel.Type != pb.ChannelType_GROUP || accountErr == nil) {
+			t.Fatalf("%s: group name is %v and has an account", name, channel.Type)
+		}
+	}
+}
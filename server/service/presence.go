@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+)
+
+func (s *ChatService) SetPresence(ctx context.Context, req *pb.SetPresenceRequest) (*emptypb.Empty, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.hub.SetStatus(user, req.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+
+	s.broadcastPresence(user)
+
+	return &emptypb.Empty{}, nil
+}
+
+// broadcastPresence sends the current presence of user to the users sharing a channel with user
+func (s *ChatService) broadcastPresence(user string) {
+	p, ok := s.hub.Presence(user)
+	if !ok {
+		return
+	}
+
+	event := &pb.ServerEvent{
+		Event: &pb.ServerEvent_Presence{
+			Presence: presenceProto(user, p),
+		},
+	}
+
+	for _, peer := range s.hub.Peers(user) {
+		// presence is ephemeral, offline peers get it from ListChannels
+		_ = s.hub.Deliver(peer, event)
+	}
+}
+
+// presenceProto converts presence p of user, unknown users are offline without last seen time
+func presenceProto(user string, p hub.Presence) *pb.Presence {
+	res := &pb.Presence{
+		User:   user,
+		Status: p.Status,
+	}
+
+	if !p.LastSeen.IsZero() {
+		res.LastSeen = timestamppb.New(p.LastSeen)
+	}
+
+	return res
+}
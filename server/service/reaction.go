@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// maxEmojiLength is the maximum length of a reaction in bytes, enough for emoji sequences
+const maxEmojiLength = 32
+
+func (s *ChatService) AddReaction(ctx context.Context, req *pb.ReactionRequest) (*emptypb.Empty, error) {
+	return s.react(ctx, req, true)
+}
+
+func (s *ChatService) RemoveReaction(ctx context.Context, req *pb.ReactionRequest) (*emptypb.Empty, error) {
+	return s.react(ctx, req, false)
+}
+
+// react adds or removes the reaction of the user to a message and broadcasts the change.
+// Adding an existing or removing a missing reaction changes nothing.
+func (s *ChatService) react(ctx context.Context, req *pb.ReactionRequest, add bool) (*emptypb.Empty, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	emoji := req.GetEmoji()
+	if emoji == "" || len(emoji) > maxEmojiLength || !utf8.ValidString(emoji) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid reaction %q", emoji)
+	}
+
+	msg, _, err := s.accessibleMessage(ctx, user, req.GetMessageId())
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.conversationLocks.lock(store.ChannelKey(msg.GetChannel(), msg.GetSender())).Unlock()
+
+	changed := false
+	msg, err = s.messages.Update(ctx, msg.GetId(), func(msg *pb.Message) error {
+		if msg.GetDeleted() {
+			return status.Errorf(codes.FailedPrecondition, "message %s is deleted", msg.GetId())
+		}
+
+		if add {
+			msg.Reactions, changed = addReaction(msg.GetReactions(), emoji, user)
+		} else {
+			msg.Reactions, changed = removeReaction(msg.GetReactions(), emoji, user)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, updateError(err, "failed to update reactions")
+	}
+
+	if changed {
+		s.broadcastReaction(user, emoji, add, msg)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// broadcastReaction sends the reaction change of user to the participants of the channel of msg
+func (s *ChatService) broadcastReaction(user, emoji string, added bool, msg *pb.Message) {
+	event := &pb.ServerEvent{
+		Event: &pb.ServerEvent_Reaction{
+			Reaction: &pb.ReactionUpdate{
+				MessageId: msg.GetId(),
+				Channel:   msg.GetChannel(),
+				User:      user,
+				Emoji:     emoji,
+				Added:     added,
+				Reactions: msg.GetReactions(),
+			},
+		},
+	}
+
+	for _, receiver := range s.participants(msg) {
+		// reactions are ephemeral, offline users see them in the history
+		_ = s.hub.Deliver(receiver, event)
+	}
+}
+
+// addReaction adds user to the reaction emoji, changed is false when user already reacted with emoji
+func addReaction(reactions []*pb.Reaction, emoji, user string) (res []*pb.Reaction, changed bool) {
+	for _, r := range reactions {
+		if r.GetEmoji() != emoji {
+			continue
+		}
+
+		for _, u := range r.GetUsers() {
+			if u == user {
+				return reactions, false
+			}
+		}
+
+		r.Users = append(r.Users, user)
+		r.Count = int64(len(r.Users))
+
+		return reactions, true
+	}
+
+	return append(reactions, &pb.Reaction{
+		Emoji: emoji,
+		Count: 1,
+		Users: []string{user},
+	}), true
+}
+
+// removeReaction removes user from the reaction emoji, a reaction without users is removed.
+// changed is false when user did not react with emoji.
+func removeReaction(reactions []*pb.Reaction, emoji, user string) (res []*pb.Reaction, changed bool) {
+	res = make([]*pb.Reaction, 0, len(reactions))
+	for _, r := range reactions {
+		if r.GetEmoji() == emoji {
+			users := make([]string, 0, len(r.GetUsers()))
+			for _, u := range r.GetUsers() {
+				if u == user {
+					changed = true
+					continue
+				}
+
+				users = append(users, u)
+			}
+
+			if len(users) == 0 {
+				continue
+			}
+
+			r.Users = users
+			r.Count = int64(len(users))
+		}
+
+		res = append(res, r)
+	}
+
+	return res, changed
+}
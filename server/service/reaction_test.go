@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// reactionOf waits for a reaction update on stream, it returns nil when none arrives in time
+func reactionOf(stream *connectStream, wait time.Duration) *pb.ReactionUpdate {
+	timeout := time.After(wait)
+	for {
+		select {
+		case res := <-stream.sent:
+			if reaction := res.GetEvent().GetReaction(); reaction != nil {
+				return reaction
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+func TestReactionsCountUsersOnce(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := openConnect(t, s, "alice", &pb.ConnectRequest{Events: true})
+	bob := connectAs(t, s, "bob")
+
+	msg, err := s.sendMessage(context.Background(), "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	react := &pb.ReactionRequest{MessageId: msg.GetId(), Emoji: "👍"}
+
+	_, err = s.AddReaction(bob, react)
+	if err != nil {
+		t.Fatalf("AddReaction: %v", err)
+	}
+
+	update := reactionOf(alice, time.Second)
+	if update.GetUser() != "bob" || !update.GetAdded() || len(update.GetReactions()) != 1 || update.GetReactions()[0].GetCount() != 1 {
+		t.Fatalf("alice got %v, want bob adding the first reaction", update)
+	}
+
+	// reacting twice with the same emoji changes nothing
+	_, err = s.AddReaction(bob, react)
+	if err != nil {
+		t.Fatalf("AddReaction: %v", err)
+	}
+
+	if update := reactionOf(alice, 100*time.Millisecond); update != nil {
+		t.Fatalf("repeated reaction sent %v", update)
+	}
+
+	_, err = s.RemoveReaction(bob, react)
+	if err != nil {
+		t.Fatalf("RemoveReaction: %v", err)
+	}
+
+	update = reactionOf(alice, time.Second)
+	if update.GetAdded() || len(update.GetReactions()) != 0 {
+		t.Fatalf("alice got %v, want the reaction removed", update)
+	}
+
+	stored, err := s.messages.Get(context.Background(), msg.GetId())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if len(stored.GetReactions()) != 0 {
+		t.Fatalf("stored reactions = %v, want none", stored.GetReactions())
+	}
+}
+
+func TestReactionsAreChecked(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+	bob := connectAs(t, s, "bob")
+	carol := connectAs(t, s, "carol")
+
+	msg, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	for _, emoji := range []string{"", strings.Repeat("👍", maxEmojiLength), "\xff"} {
+		_, err = s.AddReaction(bob, &pb.ReactionRequest{MessageId: msg.GetId(), Emoji: emoji})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("AddReaction of %q = %v, want InvalidArgument", emoji, err)
+		}
+	}
+
+	_, err = s.AddReaction(carol, &pb.ReactionRequest{MessageId: msg.GetId(), Emoji: "👍"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("AddReaction by an outsider = %v, want NotFound", err)
+	}
+
+	_, err = s.DeleteMessage(alice, &pb.DeleteMessageRequest{MessageId: msg.GetId()})
+	if err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+
+	_, err = s.AddReaction(bob, &pb.ReactionRequest{MessageId: msg.GetId(), Emoji: "👍"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("AddReaction to a deleted message = %v, want FailedPrecondition", err)
+	}
+}
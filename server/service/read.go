@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func (s *ChatService) MarkRead(ctx context.Context, req *pb.MarkReadRequest) (*emptypb.Empty, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.conversationKey(user, req.GetChannel())
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.messages.Get(ctx, req.GetUpToMessageId())
+	if errors.Is(err, store.ErrNotFound) || (err == nil && store.ChannelKey(msg.GetChannel(), msg.GetSender()) != key) {
+		return nil, status.Errorf(codes.NotFound, "message %s not found in %s", req.GetUpToMessageId(), req.GetChannel().GetName())
+	}
+
+	if err != nil {
+		log.Printf("failed to get message: %v", err)
+		return nil, status.Error(codes.Internal, "failed to mark read")
+	}
+
+	err = s.readMarkers.SetReadMarker(ctx, user, key, msg.GetSeq())
+	if err != nil {
+		log.Printf("failed to set read marker: %v", err)
+		return nil, status.Error(codes.Internal, "failed to mark read")
+	}
+
+	// the other participant of a direct conversation is told how far user has read
+	other := req.GetChannel().GetName()
+	if req.GetChannel().GetType() == pb.ChannelType_USER && other != user {
+		_ = s.hub.Deliver(other, &pb.ServerEvent{
+			Event: &pb.ServerEvent_Receipt{
+				Receipt: &pb.Receipt{
+					MessageId: msg.GetId(),
+					Channel:   msg.GetChannel(),
+					User:      user,
+					Type:      pb.ReceiptType_READ,
+					Time:      timestamppb.New(time.Now()),
+				},
+			},
+		})
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// unreadCount returns the number of messages of others in channel after the read marker of user.
+// ok is false when user does not take part in channel.
+func (s *ChatService) unreadCount(ctx context.Context, user string, channel hub.Channel) (int64, bool, error) {
+	if channel.Type == pb.ChannelType_GROUP && !channel.HasUser(user) {
+		return 0, false, nil
+	}
+
+	key := store.ChannelKey(&pb.Channel{Type: channel.Type, Name: channel.Name}, user)
+
+	marker, err := s.readMarkers.ReadMarker(ctx, user, key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	count, err := s.messages.Count(ctx, key, marker, user)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return count, true, nil
+}
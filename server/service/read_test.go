@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// unreadOf returns the unread count of channel listed for the user of ctx
+func unreadOf(t *testing.T, s *ChatService, ctx context.Context, channel string) int64 {
+	t.Helper()
+
+	res, err := s.ListChannels(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+
+	return res.GetUnreadCounts()[channel]
+}
+
+func TestMarkReadUpdatesUnreadCounts(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := openConnect(t, s, "alice", &pb.ConnectRequest{Events: true})
+	bob := connectAs(t, s, "bob")
+	carol := connectAs(t, s, "carol")
+
+	var messages []*pb.Message
+	for i := 0; i < 3; i++ {
+		msg, err := s.sendMessage(context.Background(), "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "hello"})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	if unread := unreadOf(t, s, bob, "alice"); unread != 3 {
+		t.Fatalf("bob has %d unread messages, want 3", unread)
+	}
+
+	aliceChannel := &pb.Channel{Type: pb.ChannelType_USER, Name: "alice"}
+
+	_, err := s.MarkRead(bob, &pb.MarkReadRequest{Channel: aliceChannel, UpToMessageId: messages[1].GetId()})
+	if err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+
+	receipt := receiptOf(alice, time.Second)
+	if receipt.GetMessageId() != messages[1].GetId() || receipt.GetUser() != "bob" || receipt.GetType() != pb.ReceiptType_READ {
+		t.Fatalf("receipt = %v, want bob read up to the second message", receipt)
+	}
+
+	if unread := unreadOf(t, s, bob, "alice"); unread != 1 {
+		t.Fatalf("bob has %d unread messages after marking, want 1", unread)
+	}
+
+	// replying reads the conversation, own messages are never unread
+	_, err = s.sendMessage(bob, "bob", &pb.SendMessageRequest{Receiver: "alice", Message: "hi"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	if unread := unreadOf(t, s, bob, "alice"); unread != 0 {
+		t.Fatalf("bob has %d unread messages after replying, want 0", unread)
+	}
+
+	// a message of another conversation can not be marked
+	_, err = s.MarkRead(carol, &pb.MarkReadRequest{Channel: aliceChannel, UpToMessageId: messages[2].GetId()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("MarkRead of a message of others = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+func (s *ChatService) AckMessages(ctx context.Context, req *pb.AckMessagesRequest) (*emptypb.Empty, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ackMessages(user, req.GetMessageIds())
+
+	return &emptypb.Empty{}, nil
+}
+
+// ackMessages marks messages as delivered to user and sends delivery receipts to their senders
+func (s *ChatService) ackMessages(user string, ids []string) {
+	now := timestamppb.New(time.Now())
+
+	for _, msg := range s.hub.Ack(user, ids) {
+		// own messages echoed in groups need no receipt
+		if msg.GetSender() == user {
+			continue
+		}
+
+		// receipts are ephemeral, offline senders miss them
+		_ = s.hub.Deliver(msg.GetSender(), &pb.ServerEvent{
+			Event: &pb.ServerEvent_Receipt{
+				Receipt: &pb.Receipt{
+					MessageId: msg.GetId(),
+					Channel:   msg.GetChannel(),
+					User:      user,
+					Type:      pb.ReceiptType_DELIVERED,
+					Time:      now,
+				},
+			},
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// receiptOf waits for a receipt on stream, it returns nil when none arrives in time
+func receiptOf(stream *connectStream, wait time.Duration) *pb.Receipt {
+	timeout := time.After(wait)
+	for {
+		select {
+		case res := <-stream.sent:
+			if receipt := res.GetEvent().GetReceipt(); receipt != nil {
+				return receipt
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+func TestAckSendsDeliveryReceipt(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := openConnect(t, s, "alice", &pb.ConnectRequest{Events: true})
+	bob := connectAs(t, s, "bob")
+
+	msg, err := s.sendMessage(context.Background(), "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	_, err = s.AckMessages(bob, &pb.AckMessagesRequest{MessageIds: []string{msg.GetId()}})
+	if err != nil {
+		t.Fatalf("AckMessages: %v", err)
+	}
+
+	receipt := receiptOf(alice, time.Second)
+	if receipt.GetMessageId() != msg.GetId() || receipt.GetUser() != "bob" || receipt.GetType() != pb.ReceiptType_DELIVERED {
+		t.Fatalf("receipt = %v, want delivery of the message to bob", receipt)
+	}
+
+	// a message is acknowledged only once
+	_, err = s.AckMessages(bob, &pb.AckMessagesRequest{MessageIds: []string{msg.GetId()}})
+	if err != nil {
+		t.Fatalf("AckMessages: %v", err)
+	}
+
+	if receipt := receiptOf(alice, 100*time.Millisecond); receipt != nil {
+		t.Fatalf("second ack sent receipt %v", receipt)
+	}
+}
+
+func TestAckOfOwnGroupMessageSendsNoReceipt(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := openConnect(t, s, "alice", &pb.ConnectRequest{Events: true})
+	ctx := auth.NewContext(context.Background(), auth.Principal{Name: "alice"})
+
+	_, err := s.CreateGroupChat(ctx, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	msg, err := s.sendMessage(ctx, "alice", &pb.SendMessageRequest{Receiver: "team", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	_, err = s.AckMessages(ctx, &pb.AckMessagesRequest{MessageIds: []string{msg.GetId()}})
+	if err != nil {
+		t.Fatalf("AckMessages: %v", err)
+	}
+
+	if receipt := receiptOf(alice, 100*time.Millisecond); receipt != nil {
+		t.Fatalf("ack of an own message sent receipt %v", receipt)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+const replayBatchSize = 100
+
+// seqTracker remembers the last sequence number sent per conversation,
+// so that messages replayed from storage are not sent again when they are
+// also found in the offline backlog or the live queue
+type seqTracker map[string]int64
+
+// next reports whether msg comes after the last message sent in its conversation
+// and records it as sent
+func (t seqTracker) next(msg *pb.Message) bool {
+	key := store.ChannelKey(msg.GetChannel(), msg.GetSender())
+	if msg.GetSeq() <= t[key] {
+		return false
+	}
+
+	t[key] = msg.GetSeq()
+
+	return true
+}
+
+// replay sends the stored messages following the cursors of user in order.
+// Channels user can not access anymore are skipped.
+func (s *ChatService) replay(ctx context.Context, user string, cursors []*pb.ChannelCursor, sent seqTracker, send func(*pb.Message) error) error {
+	for _, cursor := range cursors {
+		key, err := s.conversationKey(user, cursor.GetChannel())
+		if err != nil {
+			log.Printf("skipping replay of %s for %s: %v", cursor.GetChannel().GetName(), user, err)
+			continue
+		}
+
+		after := cursor.GetSeq()
+		sent[key] = after
+
+		err = s.replayConversation(ctx, key, after, sent, send)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaySpilled replays the conversations the hub spilled to storage because
+// the queue of session was full. It does nothing until the queue is drained,
+// so that the replay does not overtake messages queued before the spill.
+func (s *ChatService) replaySpilled(ctx context.Context, session *hub.Session, sent seqTracker, send func(*pb.Message) error) error {
+	if len(session.Events()) > 0 {
+		return nil
+	}
+
+	for _, msg := range session.TakeSpilled() {
+		err := s.replayConversation(ctx, store.ChannelKey(msg.GetChannel(), msg.GetSender()), msg.GetSeq()-1, sent, send)
+		if err != nil {
+			log.Printf("failed to replay spilled messages of %s: %v", session.User, err)
+			return status.Error(codes.Internal, "failed to replay messages")
+		}
+	}
+
+	return nil
+}
+
+// replayConversation sends the stored messages of the conversation key after seq in order
+func (s *ChatService) replayConversation(ctx context.Context, key string, after int64, sent seqTracker, send func(*pb.Message) error) error {
+	for {
+		messages, err := s.messages.After(ctx, key, after, replayBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			if !sent.next(msg) {
+				continue
+			}
+
+			err = send(msg)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < replayBatchSize {
+			return nil
+		}
+
+		after = messages[len(messages)-1].GetSeq()
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"io"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+// Chat connects the authenticated user and serves both directions of the stream.
+// Events for the user are sent from this goroutine only, frames of the client
+// are handled by a separate receiving goroutine.
+func (s *ChatService) Chat(stream pb.ChatService_ChatServer) error {
+	user := principalName(stream.Context())
+	if user == "" {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	session, err := s.connect(user)
+	if err != nil {
+		return err
+	}
+
+	defer s.disconnect(session)
+
+	send := func(msg *pb.Message) error {
+		return stream.Send(&pb.ServerEvent{
+			Event: &pb.ServerEvent_Message{
+				Message: msg,
+			},
+		})
+	}
+
+	// messages spilled to storage by a full queue are replayed from there,
+	// their copies still queued live are skipped
+	sent := seqTracker{}
+
+	for _, event := range session.Backlog() {
+		if msg := event.GetMessage(); msg != nil {
+			sent.next(msg)
+		}
+
+		err = stream.Send(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	recvErr := make(chan error, 1)
+	go func() {
+		recvErr <- s.receiveEvents(user, stream)
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-session.Done():
+			return session.Err()
+		case err := <-recvErr:
+			return err
+		case event := <-session.Events():
+			if msg := event.GetMessage(); msg != nil && !sent.next(msg) {
+				continue
+			}
+
+			err := stream.Send(event)
+			if err != nil {
+				return err
+			}
+
+			err = s.replaySpilled(stream.Context(), session, sent, send)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// receiveEvents handles client frames until the client closes the stream
+func (s *ChatService) receiveEvents(user string, stream pb.ChatService_ChatServer) error {
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch e := event.GetEvent().(type) {
+		case *pb.ClientEvent_Send:
+			msg, err := s.sendMessage(stream.Context(), user, e.Send)
+			s.reply(user, sendAck(event.GetId(), msg, err))
+		case *pb.ClientEvent_Ack:
+			s.ackMessages(user, e.Ack.GetMessageIds())
+		case *pb.ClientEvent_Typing:
+			err = s.setTyping(user, e.Typing)
+			if err != nil {
+				log.Println(err)
+			}
+		case *pb.ClientEvent_Control:
+			switch e.Control.GetType() {
+			case pb.ControlType_PING:
+				s.reply(user, controlEvent(pb.ControlType_PONG))
+			case pb.ControlType_CLOSE:
+				return nil
+			}
+		}
+	}
+}
+
+// reply queues event on the own session of user
+func (s *ChatService) reply(user string, event *pb.ServerEvent) {
+	err := s.hub.Deliver(user, event)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func sendAck(id string, msg *pb.Message, err error) *pb.ServerEvent {
+	ack := &pb.SendAck{
+		Id:        id,
+		MessageId: msg.GetId(),
+		Seq:       msg.GetSeq(),
+	}
+
+	if err != nil {
+		st := status.Convert(err)
+		ack.Code = int32(st.Code())
+		ack.Error = st.Message()
+	}
+
+	return &pb.ServerEvent{
+		Event: &pb.ServerEvent_SendAck{
+			SendAck: ack,
+		},
+	}
+}
+
+func controlEvent(controlType pb.ControlType) *pb.ServerEvent {
+	return &pb.ServerEvent{
+		Event: &pb.ServerEvent_Control{
+			Control: &pb.Control{
+				Type: controlType,
+			},
+		},
+	}
+}
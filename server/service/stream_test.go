@@ -0,0 +1,479 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/auth"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+// connectStream is a server side Connect stream collecting the sent messages
+type connectStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *pb.ConnectResponse
+}
+
+func (s *connectStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *connectStream) Send(res *pb.ConnectResponse) error {
+	s.sent <- res
+	return nil
+}
+
+// openConnect runs Connect of user until the test ends and returns its stream
+func openConnect(t *testing.T, s *ChatService, user string, req *pb.ConnectRequest) *connectStream {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(auth.NewContext(context.Background(), auth.Principal{Name: user}))
+	stream := &connectStream{ctx: ctx, sent: make(chan *pb.ConnectResponse, 16)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Connect(req, stream)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	for !s.hub.IsConnected(user) {
+		time.Sleep(time.Millisecond)
+	}
+
+	return stream
+}
+
+// typingOf waits for a typing event on stream, it returns nil when none arrives in time
+func typingOf(stream *connectStream, wait time.Duration) *pb.Typing {
+	timeout := time.After(wait)
+	for {
+		select {
+		case res := <-stream.sent:
+			if typing := res.GetEvent().GetTyping(); typing != nil {
+				return typing
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+func TestConnectDeliversEventsWhenAsked(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+
+	bob := openConnect(t, s, "bob", &pb.ConnectRequest{Events: true})
+	carol := openConnect(t, s, "carol", &pb.ConnectRequest{})
+	connectAs(t, s, "alice")
+
+	for _, receiver := range []string{"bob", "carol"} {
+		err := s.setTyping("alice", &pb.Typing{Channel: &pb.Channel{Type: pb.ChannelType_USER, Name: receiver}, Typing: true})
+		if err != nil {
+			t.Fatalf("setTyping: %v", err)
+		}
+	}
+
+	typing := typingOf(bob, time.Second)
+	if typing == nil || typing.GetUser() != "alice" || !typing.GetTyping() {
+		t.Fatalf("bob got typing %v, want alice typing", typing)
+	}
+
+	if typing := typingOf(carol, 100*time.Millisecond); typing != nil {
+		t.Fatalf("carol got typing %v without asking for events", typing)
+	}
+}
+
+func TestConnectSendsMessagesAsMessages(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+
+	bob := openConnect(t, s, "bob", &pb.ConnectRequest{Events: true})
+	alice := connectAs(t, s, "alice")
+
+	_, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case res := <-bob.sent:
+			if res.GetEvent().GetMessage() != nil {
+				t.Fatalf("message sent as event %v", res)
+			}
+
+			if msg := res.GetMessage(); msg != nil {
+				if msg.GetMessage() != "hello" || msg.GetSender() != "alice" {
+					t.Fatalf("bob got %v, want hello of alice", msg)
+				}
+
+				return
+			}
+		case <-timeout:
+			t.Fatal("bob got no message")
+		}
+	}
+}
+
+func TestConnectReplaysMessagesSpilledToStorage(t *testing.T) {
+	const messages = 50
+
+	s := NewChatService(hub.New(hub.Config{QueueSize: 2, Overflow: hub.SpillToStorage}), store.NewMemoryStore(), Config{})
+
+	// bob does not read while alice sends, his stream and queue fill up
+	bob := openConnect(t, s, "bob", &pb.ConnectRequest{})
+	alice := connectAs(t, s, "alice")
+
+	for i := 0; i < messages; i++ {
+		_, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+	}
+
+	for i := 0; i < messages; i++ {
+		select {
+		case res := <-bob.sent:
+			if msg := res.GetMessage(); msg.GetMessage() != fmt.Sprint(i) {
+				t.Fatalf("bob got %v, want message %d", msg, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("bob got %d messages, want %d", i, messages)
+		}
+	}
+}
+
+func TestConnectEndDisconnectsUser(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := openConnect(t, s, "alice", &pb.ConnectRequest{Events: true})
+
+	ctx, cancel := context.WithCancel(auth.NewContext(context.Background(), auth.Principal{Name: "bob"}))
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Connect(&pb.ConnectRequest{}, &connectStream{ctx: ctx, sent: make(chan *pb.ConnectResponse, 16)})
+	}()
+
+	for !s.hub.IsConnected("bob") {
+		time.Sleep(time.Millisecond)
+	}
+
+	// a second stream of bob replaces the first one
+	second := openConnect(t, s, "bob", &pb.ConnectRequest{})
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.Aborted {
+			t.Fatalf("replaced Connect returned %v, want Aborted", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("replaced Connect did not return")
+	}
+
+	cancel()
+
+	if !s.hub.IsConnected("bob") {
+		t.Fatal("end of the replaced stream disconnected bob")
+	}
+
+	_, err := s.sendMessage(connectAs(t, s, "carol"), "carol", &pb.SendMessageRequest{Receiver: "bob", Message: "hello"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	select {
+	case res := <-second.sent:
+		if res.GetMessage().GetMessage() != "hello" {
+			t.Fatalf("second stream got %v, want hello", res)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second stream got no message")
+	}
+
+	ctx, cancel = context.WithCancel(auth.NewContext(context.Background(), auth.Principal{Name: "dave"}))
+	go func() {
+		done <- s.Connect(&pb.ConnectRequest{}, &connectStream{ctx: ctx, sent: make(chan *pb.ConnectResponse, 16)})
+	}()
+
+	for !s.hub.IsConnected("dave") {
+		time.Sleep(time.Millisecond)
+	}
+
+	// dave is a contact of alice
+	_, err = s.sendMessage(context.Background(), "alice", &pb.SendMessageRequest{Receiver: "dave", Message: "hi"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Connect returned %v after the client left", err)
+	}
+
+	if s.hub.IsConnected("dave") {
+		t.Fatal("dave is still connected after his stream ended")
+	}
+
+	_, err = s.getAuthUser(auth.NewContext(context.Background(), auth.Principal{Name: "dave"}))
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("getAuthUser after the stream ended = %v, want Unauthenticated", err)
+	}
+
+	// the others see dave go offline
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case res := <-alice.sent:
+			if presence := res.GetEvent().GetPresence(); presence.GetUser() == "dave" && presence.GetStatus() == pb.PresenceStatus_OFFLINE {
+				return
+			}
+		case <-timeout:
+			t.Fatal("alice was not told that dave went offline")
+		}
+	}
+}
+
+// chatStream is the server side of a Chat stream driven by the test
+type chatStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	received chan *pb.ClientEvent
+	sent     chan *pb.ServerEvent
+}
+
+func (s *chatStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *chatStream) Recv() (*pb.ClientEvent, error) {
+	event, ok := <-s.received
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return event, nil
+}
+
+func (s *chatStream) Send(event *pb.ServerEvent) error {
+	s.sent <- event
+	return nil
+}
+
+// openChat runs Chat of user until the test ends, the returned channel gets its result
+func openChat(t *testing.T, s *ChatService, user string) (*chatStream, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(auth.NewContext(context.Background(), auth.Principal{Name: user}))
+	stream := &chatStream{ctx: ctx, received: make(chan *pb.ClientEvent, 16), sent: make(chan *pb.ServerEvent, 16)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Chat(stream)
+	}()
+
+	t.Cleanup(cancel)
+
+	for !s.hub.IsConnected(user) {
+		time.Sleep(time.Millisecond)
+	}
+
+	return stream, done
+}
+
+// nextEvent waits for the next event on stream which matches
+func nextEvent(t *testing.T, stream *chatStream, matches func(*pb.ServerEvent) bool) *pb.ServerEvent {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-stream.sent:
+			if matches(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("no matching event")
+			return nil
+		}
+	}
+}
+
+func TestChatSendsAndReceives(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	bob, _ := openChat(t, s, "bob")
+	alice, aliceDone := openChat(t, s, "alice")
+
+	alice.received <- &pb.ClientEvent{
+		Id:    "1",
+		Event: &pb.ClientEvent_Send{Send: &pb.SendMessageRequest{Receiver: "bob", Message: "hello"}},
+	}
+
+	ack := nextEvent(t, alice, func(e *pb.ServerEvent) bool { return e.GetSendAck() != nil }).GetSendAck()
+	if ack.GetId() != "1" || ack.GetCode() != 0 || ack.GetSeq() != 1 || ack.GetMessageId() == "" {
+		t.Fatalf("ack = %v, want success of request 1", ack)
+	}
+
+	msg := nextEvent(t, bob, func(e *pb.ServerEvent) bool { return e.GetMessage() != nil }).GetMessage()
+	if msg.GetId() != ack.GetMessageId() || msg.GetMessage() != "hello" {
+		t.Fatalf("bob got %v, want the acked message", msg)
+	}
+
+	alice.received <- &pb.ClientEvent{
+		Id:    "2",
+		Event: &pb.ClientEvent_Send{Send: &pb.SendMessageRequest{Receiver: "nobody", Message: "lost"}},
+	}
+
+	ack = nextEvent(t, alice, func(e *pb.ServerEvent) bool { return e.GetSendAck() != nil }).GetSendAck()
+	if ack.GetId() != "2" || codes.Code(ack.GetCode()) != codes.NotFound {
+		t.Fatalf("ack = %v, want NotFound for request 2", ack)
+	}
+
+	alice.received <- &pb.ClientEvent{Event: &pb.ClientEvent_Control{Control: &pb.Control{Type: pb.ControlType_PING}}}
+	nextEvent(t, alice, func(e *pb.ServerEvent) bool { return e.GetControl().GetType() == pb.ControlType_PONG })
+
+	close(alice.received)
+
+	select {
+	case err := <-aliceDone:
+		if err != nil {
+			t.Fatalf("Chat returned %v after the client closed the stream", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Chat did not return after the client closed the stream")
+	}
+
+	if s.hub.IsConnected("alice") {
+		t.Fatal("alice is still connected after closing the stream")
+	}
+}
+
+func TestChatReplaysMessagesSpilledToStorage(t *testing.T) {
+	const messages = 50
+
+	s := NewChatService(hub.New(hub.Config{QueueSize: 2, Overflow: hub.SpillToStorage}), store.NewMemoryStore(), Config{})
+	bob, _ := openChat(t, s, "bob")
+	alice := connectAs(t, s, "alice")
+
+	for i := 0; i < messages; i++ {
+		_, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+	}
+
+	for i := 0; i < messages; i++ {
+		msg := nextEvent(t, bob, func(e *pb.ServerEvent) bool { return e.GetMessage() != nil }).GetMessage()
+		if msg.GetMessage() != fmt.Sprint(i) {
+			t.Fatalf("bob got %v, want message %d", msg, i)
+		}
+	}
+}
+
+// messagesOf collects the message texts sent on stream until none arrives for wait
+func messagesOf(stream *connectStream, wait time.Duration) []string {
+	var messages []string
+	for {
+		select {
+		case res := <-stream.sent:
+			if msg := res.GetMessage(); msg != nil {
+				messages = append(messages, msg.GetMessage())
+			}
+		case <-time.After(wait):
+			return messages
+		}
+	}
+}
+
+func TestConnectDeliversQueuedMessages(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+
+	session, err := s.connect("bob")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	s.disconnect(session)
+
+	var ids []string
+	for _, text := range []string{"1", "2"} {
+		msg, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: text})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+
+		ids = append(ids, msg.GetId())
+	}
+
+	bob := openConnect(t, s, "bob", &pb.ConnectRequest{})
+	if got := messagesOf(bob, 100*time.Millisecond); fmt.Sprint(got) != "[1 2]" {
+		t.Fatalf("bob got %v on connect, want [1 2]", got)
+	}
+
+	_, err = s.AckMessages(auth.NewContext(context.Background(), auth.Principal{Name: "bob"}), &pb.AckMessagesRequest{MessageIds: ids[:1]})
+	if err != nil {
+		t.Fatalf("AckMessages: %v", err)
+	}
+
+	// only the unacknowledged message is delivered again
+	bob = openConnect(t, s, "bob", &pb.ConnectRequest{})
+	if got := messagesOf(bob, 100*time.Millisecond); fmt.Sprint(got) != "[2]" {
+		t.Fatalf("bob got %v on the next connect, want [2]", got)
+	}
+}
+
+func TestConnectResumesFromCursors(t *testing.T) {
+	s := NewChatService(hub.New(hub.Config{}), store.NewMemoryStore(), Config{})
+	alice := connectAs(t, s, "alice")
+
+	session, err := s.connect("bob")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	s.disconnect(session)
+
+	_, err = s.CreateGroupChat(alice, &pb.CreateGroupChatRequest{ChannelName: "team"})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	var ids []string
+	for i := 1; i <= 6; i++ {
+		msg, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+
+		ids = append(ids, msg.GetId())
+	}
+
+	// the first five were received on another device, the last one is still queued
+	s.hub.Ack("bob", ids[:5])
+
+	bob := openConnect(t, s, "bob", &pb.ConnectRequest{
+		Cursors: []*pb.ChannelCursor{
+			{Channel: &pb.Channel{Type: pb.ChannelType_USER, Name: "alice"}, Seq: 3},
+			// channels bob can not access are skipped
+			{Channel: &pb.Channel{Type: pb.ChannelType_GROUP, Name: "team"}},
+		},
+	})
+
+	if got := messagesOf(bob, 100*time.Millisecond); fmt.Sprint(got) != "[4 5 6]" {
+		t.Fatalf("bob got %v, want the messages after the cursor once", got)
+	}
+}
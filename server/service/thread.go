@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func (s *ChatService) GetThread(ctx context.Context, req *pb.GetThreadRequest) (*pb.GetThreadResponse, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	root, _, err := s.accessibleMessage(ctx, user, req.GetMessageId())
+	if err != nil {
+		return nil, err
+	}
+
+	// the thread of a reply is the thread of its root
+	if root.GetParentId() != "" {
+		root, _, err = s.accessibleMessage(ctx, user, root.GetParentId())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	after, err := decodeCursor(req.GetCursor())
+	if err != nil {
+		return nil, err
+	}
+
+	limit := pageSize(req.GetPageSize())
+	key := store.ChannelKey(root.GetChannel(), root.GetSender())
+
+	replies, err := s.messages.Replies(ctx, key, root.GetId(), after, limit)
+	if err != nil {
+		log.Printf("failed to fetch thread: %v", err)
+		return nil, status.Error(codes.Internal, "failed to fetch thread")
+	}
+
+	res := &pb.GetThreadResponse{
+		Root:     root,
+		Messages: replies,
+	}
+
+	if len(replies) == limit {
+		res.NextCursor = encodeCursor(replies[len(replies)-1].GetSeq())
+	}
+
+	return res, nil
+}
+
+// threadRoot returns the id of the root message of the thread a reply to message parentID
+// in the conversation key belongs to, it is empty when parentID is empty
+func (s *ChatService) threadRoot(ctx context.Context, key, parentID string) (string, error) {
+	if parentID == "" {
+		return "", nil
+	}
+
+	parent, err := s.messages.Get(ctx, parentID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && store.ChannelKey(parent.GetChannel(), parent.GetSender()) != key) {
+		return "", status.Errorf(codes.NotFound, "parent message %s not found", parentID)
+	}
+
+	if err != nil {
+		log.Printf("failed to get parent message: %v", err)
+		return "", status.Error(codes.Internal, "failed to store message")
+	}
+
+	if parent.GetDeleted() {
+		return "", status.Errorf(codes.FailedPrecondition, "parent message %s is deleted", parentID)
+	}
+
+	// threads are one level deep, a reply to a reply joins the thread of its root
+	if parent.GetParentId() != "" {
+		return parent.GetParentId(), nil
+	}
+
+	return parent.GetId(), nil
+}
+
+// countReply changes the reply count of the root message id by delta
+func (s *ChatService) countReply(ctx context.Context, id string, delta int64) {
+	_, err := s.messages.Update(ctx, id, func(msg *pb.Message) error {
+		msg.ReplyCount += delta
+		if msg.ReplyCount < 0 {
+			msg.ReplyCount = 0
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to count reply: %v", err)
+	}
+}
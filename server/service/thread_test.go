@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+	"github.com/vitthalaa/go-grpc-chat/server/store"
+)
+
+func TestDeletedReplyIsNotCounted(t *testing.T) {
+	st := store.NewMemoryStore()
+	s := NewChatService(hub.New(hub.Config{}), st, Config{})
+	alice := connectAs(t, s, "alice")
+	connectAs(t, s, "bob")
+
+	root, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: "root"})
+	if err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+
+	var replies []*pb.Message
+	for _, text := range []string{"first", "second"} {
+		reply, err := s.sendMessage(alice, "alice", &pb.SendMessageRequest{Receiver: "bob", Message: text, ParentId: root.GetId()})
+		if err != nil {
+			t.Fatalf("sendMessage: %v", err)
+		}
+
+		replies = append(replies, reply)
+	}
+
+	_, err = s.DeleteMessage(alice, &pb.DeleteMessageRequest{MessageId: replies[0].GetId()})
+	if err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+
+	root, err = st.Get(context.Background(), root.GetId())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if root.GetReplyCount() != 1 {
+		t.Fatalf("reply count = %d, want 1", root.GetReplyCount())
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+	"github.com/vitthalaa/go-grpc-chat/server/hub"
+)
+
+// typingTimeout is how long a started typing state lasts without being refreshed
+const typingTimeout = 6 * time.Second
+
+type typingKey struct {
+	user    string
+	channel string
+}
+
+// typingTracker keeps the expiry timers of users currently typing
+type typingTracker struct {
+	mu     sync.Mutex
+	timers map[typingKey]*time.Timer
+}
+
+func newTypingTracker() *typingTracker {
+	return &typingTracker{
+		timers: make(map[typingKey]*time.Timer),
+	}
+}
+
+// start marks user as typing in channel until timeout, then expire is called.
+// It reports whether user was not typing in channel before.
+func (t *typingTracker) start(user, channel string, timeout time.Duration, expire func()) bool {
+	key := typingKey{user: user, channel: channel}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	old, typing := t.timers[key]
+	if typing {
+		old.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		t.mu.Lock()
+		current := t.timers[key] == timer
+		if current {
+			delete(t.timers, key)
+		}
+		t.mu.Unlock()
+
+		if current {
+			expire()
+		}
+	})
+
+	t.timers[key] = timer
+
+	return !typing
+}
+
+// stop marks user as not typing in channel and reports whether user was typing
+func (t *typingTracker) stop(user, channel string) bool {
+	key := typingKey{user: user, channel: channel}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timer, ok := t.timers[key]
+	if !ok {
+		return false
+	}
+
+	timer.Stop()
+	delete(t.timers, key)
+
+	return true
+}
+
+// stopUser marks user as not typing anywhere and returns the channels user was typing in
+func (t *typingTracker) stopUser(user string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var channels []string
+	for key, timer := range t.timers {
+		if key.user != user {
+			continue
+		}
+
+		timer.Stop()
+		delete(t.timers, key)
+		channels = append(channels, key.channel)
+	}
+
+	return channels
+}
+
+func (s *ChatService) SetTyping(ctx context.Context, req *pb.Typing) (*emptypb.Empty, error) {
+	user, err := s.getAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.setTyping(user, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// setTyping records the typing state of user and forwards changes to the other participants of the channel
+func (s *ChatService) setTyping(user string, typing *pb.Typing) error {
+	channel, ok := s.hub.Channel(typing.GetChannel().GetName())
+	if !ok {
+		return status.Errorf(codes.NotFound, "channel %s not found", typing.GetChannel().GetName())
+	}
+
+	if channel.Type == pb.ChannelType_GROUP && !channel.HasUser(user) {
+		return status.Errorf(codes.PermissionDenied, "not a member of %s", channel.Name)
+	}
+
+	if !typing.GetTyping() {
+		if s.typing.stop(user, channel.Name) {
+			s.broadcastTyping(user, channel, false)
+		}
+
+		return nil
+	}
+
+	started := s.typing.start(user, channel.Name, typingTimeout, func() {
+		s.broadcastTyping(user, channel, false)
+	})
+
+	// refreshing a started typing state is not forwarded again
+	if started {
+		s.broadcastTyping(user, channel, true)
+	}
+
+	return nil
+}
+
+// broadcastTyping sends the typing state of user to the other participants of the channel
+func (s *ChatService) broadcastTyping(user string, channel hub.Channel, typing bool) {
+	receivers := []string{channel.Name}
+	if channel.Type == pb.ChannelType_GROUP {
+		receivers = channel.Users
+	}
+
+	event := &pb.ServerEvent{
+		Event: &pb.ServerEvent_Typing{
+			Typing: &pb.Typing{
+				Channel: &pb.Channel{
+					Type: channel.Type,
+					Name: channel.Name,
+				},
+				User:   user,
+				Typing: typing,
+			},
+		},
+	}
+
+	for _, receiver := range receivers {
+		if receiver == user {
+			continue
+		}
+
+		// typing is ephemeral, offline receivers simply miss it
+		_ = s.hub.Deliver(receiver, event)
+	}
+}
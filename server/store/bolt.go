@@ -0,0 +1,623 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+var (
+	idsBucket        = []byte("ids")
+	attachmentBucket = []byte("attachments")
+	accountBucket    = []byte("accounts")
+	groupBucket      = []byte("groups")
+	readMarkerPrefix = "read/"
+)
+
+// BoltStore is a Store persisting everything in an embedded bbolt database file.
+// Every conversation is a bucket keyed by the message sequence number,
+// which is the sequence of the bucket itself. The ids bucket maps message ids
+// to their sequence number followed by the conversation key.
+// Read markers of a user are kept in a bucket per user keyed by conversation.
+// The attachments bucket keeps the attachment info by attachment id,
+// the accounts and groups buckets keep accounts and groups encoded as JSON by name.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltStore{
+		db: db,
+	}, nil
+}
+
+func (s *BoltStore) Append(_ context.Context, key string, msg *pb.Message) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		msg.Seq = int64(seq)
+
+		value, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put(seqKey(msg.Seq), value)
+		if err != nil || msg.GetId() == "" {
+			return err
+		}
+
+		ids, err := tx.CreateBucketIfNotExists(idsBucket)
+		if err != nil {
+			return err
+		}
+
+		return ids.Put([]byte(msg.GetId()), append(seqKey(msg.Seq), key...))
+	})
+}
+
+func (s *BoltStore) Fetch(_ context.Context, key string, from, to time.Time) ([]*pb.Message, error) {
+	var messages []*pb.Message
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, v []byte) error {
+			msg, err := unmarshalMessage(v)
+			if err != nil {
+				return err
+			}
+
+			if inRange(msg, from, to) {
+				messages = append(messages, msg)
+			}
+
+			return nil
+		})
+	})
+
+	return messages, err
+}
+
+func (s *BoltStore) Last(_ context.Context, key string, before int64, limit int) ([]*pb.Message, error) {
+	var messages []*pb.Message
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+
+		// walk backwards from the newest message lower than before
+		k, v := c.Last()
+		if before > 0 {
+			k, v = c.Seek(seqKey(before))
+			if k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		for ; k != nil && len(messages) < limit; k, v = c.Prev() {
+			msg, err := unmarshalMessage(v)
+			if err != nil {
+				return err
+			}
+
+			messages = append(messages, msg)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+func (s *BoltStore) After(_ context.Context, key string, after int64, limit int) ([]*pb.Message, error) {
+	var messages []*pb.Message
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(seqKey(after + 1)); k != nil && len(messages) < limit; k, v = c.Next() {
+			msg, err := unmarshalMessage(v)
+			if err != nil {
+				return err
+			}
+
+			messages = append(messages, msg)
+		}
+
+		return nil
+	})
+
+	return messages, err
+}
+
+func (s *BoltStore) Replies(_ context.Context, key, parentID string, after int64, limit int) ([]*pb.Message, error) {
+	var replies []*pb.Message
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(seqKey(after + 1)); k != nil && len(replies) < limit; k, v = c.Next() {
+			msg, err := unmarshalMessage(v)
+			if err != nil {
+				return err
+			}
+
+			if msg.GetParentId() == parentID {
+				replies = append(replies, msg)
+			}
+		}
+
+		return nil
+	})
+
+	return replies, err
+}
+
+func (s *BoltStore) Get(_ context.Context, id string) (*pb.Message, error) {
+	var msg *pb.Message
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket, k := lookupID(tx, id)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		v := bucket.Get(k)
+		if v == nil {
+			return ErrNotFound
+		}
+
+		var err error
+		msg, err = unmarshalMessage(v)
+
+		return err
+	})
+
+	return msg, err
+}
+
+func (s *BoltStore) Update(_ context.Context, id string, update func(msg *pb.Message) error) (*pb.Message, error) {
+	var msg *pb.Message
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, k := lookupID(tx, id)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		v := bucket.Get(k)
+		if v == nil {
+			return ErrNotFound
+		}
+
+		var err error
+		msg, err = unmarshalMessage(v)
+		if err != nil {
+			return err
+		}
+
+		err = update(msg)
+		if err != nil {
+			return err
+		}
+
+		value, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(k, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (s *BoltStore) Count(_ context.Context, key string, after int64, exclude string) (int64, error) {
+	var count int64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(seqKey(after + 1)); k != nil; k, v = c.Next() {
+			msg, err := unmarshalMessage(v)
+			if err != nil {
+				return err
+			}
+
+			if msg.GetSender() != exclude && !msg.GetDeleted() {
+				count++
+			}
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+func (s *BoltStore) Delete(_ context.Context, key string, from, to time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		var keys, ids [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			msg, err := unmarshalMessage(v)
+			if err != nil {
+				return err
+			}
+
+			if inRange(msg, from, to) {
+				keys = append(keys, append([]byte(nil), k...))
+				ids = append(ids, []byte(msg.GetId()))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			err = bucket.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		if idBucket := tx.Bucket(idsBucket); idBucket != nil {
+			for _, id := range ids {
+				err = idBucket.Delete(id)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) SetReadMarker(_ context.Context, user, key string, seq int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(readMarkerPrefix + user))
+		if err != nil {
+			return err
+		}
+
+		if v := bucket.Get([]byte(key)); v != nil && int64(binary.BigEndian.Uint64(v)) >= seq {
+			return nil
+		}
+
+		return bucket.Put([]byte(key), seqKey(seq))
+	})
+}
+
+func (s *BoltStore) ReadMarker(_ context.Context, user, key string) (int64, error) {
+	var seq int64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(readMarkerPrefix + user))
+		if bucket == nil {
+			return nil
+		}
+
+		if v := bucket.Get([]byte(key)); v != nil {
+			seq = int64(binary.BigEndian.Uint64(v))
+		}
+
+		return nil
+	})
+
+	return seq, err
+}
+
+func (s *BoltStore) PutAttachment(_ context.Context, a *pb.Attachment) error {
+	value, err := proto.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(attachmentBucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(a.GetId()), value)
+	})
+}
+
+func (s *BoltStore) Attachment(_ context.Context, id string) (*pb.Attachment, error) {
+	a := &pb.Attachment{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(attachmentBucket)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		v := bucket.Get([]byte(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		return proto.Unmarshal(v, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (s *BoltStore) CreateAccount(_ context.Context, a Account) error {
+	value, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(accountBucket)
+		if err != nil {
+			return err
+		}
+
+		if bucket.Get([]byte(a.Username)) != nil {
+			return ErrExists
+		}
+
+		return bucket.Put([]byte(a.Username), value)
+	})
+}
+
+func (s *BoltStore) Account(_ context.Context, username string) (Account, error) {
+	var a Account
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(accountBucket)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		v := bucket.Get([]byte(username))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		err := json.Unmarshal(v, &a)
+		if err == nil && a.Deleted {
+			return ErrNotFound
+		}
+
+		return err
+	})
+	if err != nil {
+		return Account{}, err
+	}
+
+	return a, nil
+}
+
+func (s *BoltStore) Accounts(_ context.Context) ([]Account, error) {
+	var accounts []Account
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(accountBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, v []byte) error {
+			var a Account
+			err := json.Unmarshal(v, &a)
+			if err != nil {
+				return err
+			}
+
+			if !a.Deleted {
+				accounts = append(accounts, a)
+			}
+
+			return nil
+		})
+	})
+
+	return accounts, err
+}
+
+func (s *BoltStore) UpdateAccount(_ context.Context, a Account) error {
+	value, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := liveAccount(tx, a.Username)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(a.Username), value)
+	})
+}
+
+func (s *BoltStore) DeleteAccount(_ context.Context, username string) error {
+	value, err := json.Marshal(Account{Username: username, Deleted: true})
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := liveAccount(tx, username)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(username), value)
+	})
+}
+
+func (s *BoltStore) PutGroup(_ context.Context, g Group) error {
+	value, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(groupBucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(g.Name), value)
+	})
+}
+
+func (s *BoltStore) DeleteGroup(_ context.Context, name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(groupBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.Delete([]byte(name))
+	})
+}
+
+func (s *BoltStore) Groups(_ context.Context) ([]Group, error) {
+	var groups []Group
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(groupBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, v []byte) error {
+			var g Group
+			err := json.Unmarshal(v, &g)
+			if err != nil {
+				return err
+			}
+
+			groups = append(groups, g)
+
+			return nil
+		})
+	})
+
+	return groups, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// liveAccount returns the accounts bucket after checking it holds a not deleted account of username
+func liveAccount(tx *bolt.Tx, username string) (*bolt.Bucket, error) {
+	bucket := tx.Bucket(accountBucket)
+	if bucket == nil {
+		return nil, ErrNotFound
+	}
+
+	v := bucket.Get([]byte(username))
+	if v == nil {
+		return nil, ErrNotFound
+	}
+
+	var a Account
+	err := json.Unmarshal(v, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Deleted {
+		return nil, ErrNotFound
+	}
+
+	return bucket, nil
+}
+
+// lookupID returns the conversation bucket and the bucket key of the message id
+func lookupID(tx *bolt.Tx, id string) (*bolt.Bucket, []byte) {
+	ids := tx.Bucket(idsBucket)
+	if ids == nil {
+		return nil, nil
+	}
+
+	ref := ids.Get([]byte(id))
+	if len(ref) < 8 {
+		return nil, nil
+	}
+
+	return tx.Bucket(ref[8:]), ref[:8]
+}
+
+func seqKey(seq int64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(seq))
+
+	return k
+}
+
+func unmarshalMessage(v []byte) (*pb.Message, error) {
+	msg := &pb.Message{}
+	err := proto.Unmarshal(v, msg)
+
+	return msg, err
+}
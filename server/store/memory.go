@@ -0,0 +1,411 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+// conversation holds the messages of one conversation ordered by sequence number
+type conversation struct {
+	messages []*pb.Message
+	seq      int64
+}
+
+// messageRef locates a message by its conversation and sequence number
+type messageRef struct {
+	key string
+	seq int64
+}
+
+// MemoryStore is a Store keeping everything in memory, it is lost on restart
+type MemoryStore struct {
+	mu            sync.RWMutex
+	conversations map[string]*conversation
+	ids           map[string]messageRef
+	readMarkers   map[string]map[string]int64
+	attachments   map[string]*pb.Attachment
+	accounts      map[string]Account
+	groups        map[string]Group
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		conversations: make(map[string]*conversation),
+		ids:           make(map[string]messageRef),
+		readMarkers:   make(map[string]map[string]int64),
+		attachments:   make(map[string]*pb.Attachment),
+		accounts:      make(map[string]Account),
+		groups:        make(map[string]Group),
+	}
+}
+
+func (s *MemoryStore) Append(_ context.Context, key string, msg *pb.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.conversations[key]
+	if !ok {
+		c = &conversation{}
+		s.conversations[key] = c
+	}
+
+	c.seq++
+	msg.Seq = c.seq
+	c.messages = append(c.messages, proto.Clone(msg).(*pb.Message))
+	if msg.GetId() != "" {
+		s.ids[msg.GetId()] = messageRef{key: key, seq: msg.Seq}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Fetch(_ context.Context, key string, from, to time.Time) ([]*pb.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []*pb.Message
+
+	c, ok := s.conversations[key]
+	if !ok {
+		return res, nil
+	}
+
+	for _, msg := range c.messages {
+		if inRange(msg, from, to) {
+			res = append(res, proto.Clone(msg).(*pb.Message))
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) Last(_ context.Context, key string, before int64, limit int) ([]*pb.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.conversations[key]
+	if !ok {
+		return nil, nil
+	}
+
+	end := len(c.messages)
+	if before > 0 {
+		end = c.index(before)
+	}
+
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+
+	return cloneMessages(c.messages[start:end]), nil
+}
+
+func (s *MemoryStore) After(_ context.Context, key string, after int64, limit int) ([]*pb.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.conversations[key]
+	if !ok {
+		return nil, nil
+	}
+
+	start := c.index(after + 1)
+
+	end := start + limit
+	if end > len(c.messages) {
+		end = len(c.messages)
+	}
+
+	return cloneMessages(c.messages[start:end]), nil
+}
+
+func (s *MemoryStore) Replies(_ context.Context, key, parentID string, after int64, limit int) ([]*pb.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.conversations[key]
+	if !ok {
+		return nil, nil
+	}
+
+	var replies []*pb.Message
+	for _, msg := range c.messages[c.index(after+1):] {
+		if len(replies) == limit {
+			break
+		}
+
+		if msg.GetParentId() == parentID {
+			replies = append(replies, msg)
+		}
+	}
+
+	return cloneMessages(replies), nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (*pb.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	msg, ok := s.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return proto.Clone(msg).(*pb.Message), nil
+}
+
+func (s *MemoryStore) Update(_ context.Context, id string, update func(msg *pb.Message) error) (*pb.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	updated := proto.Clone(msg).(*pb.Message)
+	err := update(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	proto.Reset(msg)
+	proto.Merge(msg, updated)
+
+	return proto.Clone(updated).(*pb.Message), nil
+}
+
+func (s *MemoryStore) Count(_ context.Context, key string, after int64, exclude string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.conversations[key]
+	if !ok {
+		return 0, nil
+	}
+
+	var count int64
+	for _, msg := range c.messages[c.index(after+1):] {
+		if msg.GetSender() != exclude && !msg.GetDeleted() {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string, from, to time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.conversations[key]
+	if !ok {
+		return nil
+	}
+
+	messages := c.messages[:0]
+	for _, msg := range c.messages {
+		if inRange(msg, from, to) {
+			delete(s.ids, msg.GetId())
+			continue
+		}
+
+		messages = append(messages, msg)
+	}
+
+	// sequence is kept even when all messages are gone so it never goes back
+	c.messages = messages
+
+	return nil
+}
+
+func (s *MemoryStore) SetReadMarker(_ context.Context, user, key string, seq int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	markers, ok := s.readMarkers[user]
+	if !ok {
+		markers = make(map[string]int64)
+		s.readMarkers[user] = markers
+	}
+
+	if seq > markers[key] {
+		markers[key] = seq
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) ReadMarker(_ context.Context, user, key string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.readMarkers[user][key], nil
+}
+
+func (s *MemoryStore) PutAttachment(_ context.Context, a *pb.Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attachments[a.GetId()] = proto.Clone(a).(*pb.Attachment)
+
+	return nil
+}
+
+func (s *MemoryStore) Attachment(_ context.Context, id string) (*pb.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.attachments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return proto.Clone(a).(*pb.Attachment), nil
+}
+
+func (s *MemoryStore) CreateAccount(_ context.Context, a Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[a.Username]; ok {
+		return ErrExists
+	}
+
+	s.accounts[a.Username] = a
+
+	return nil
+}
+
+func (s *MemoryStore) Account(_ context.Context, username string) (Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.accounts[username]
+	if !ok || a.Deleted {
+		return Account{}, ErrNotFound
+	}
+
+	return a, nil
+}
+
+func (s *MemoryStore) Accounts(_ context.Context) ([]Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accounts := make([]Account, 0, len(s.accounts))
+	for _, a := range s.accounts {
+		if !a.Deleted {
+			accounts = append(accounts, a)
+		}
+	}
+
+	return accounts, nil
+}
+
+func (s *MemoryStore) UpdateAccount(_ context.Context, a Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.accounts[a.Username]; !ok || old.Deleted {
+		return ErrNotFound
+	}
+
+	s.accounts[a.Username] = a
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteAccount(_ context.Context, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.accounts[username]; !ok || a.Deleted {
+		return ErrNotFound
+	}
+
+	s.accounts[username] = Account{Username: username, Deleted: true}
+
+	return nil
+}
+
+func (s *MemoryStore) PutGroup(_ context.Context, g Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.groups[g.Name] = cloneGroup(g)
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteGroup(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.groups, name)
+
+	return nil
+}
+
+func (s *MemoryStore) Groups(_ context.Context) ([]Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make([]Group, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, cloneGroup(g))
+	}
+
+	return groups, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// get must be called with mu held
+func (s *MemoryStore) get(id string) (*pb.Message, bool) {
+	ref, ok := s.ids[id]
+	if !ok {
+		return nil, false
+	}
+
+	c := s.conversations[ref.key]
+	i := c.index(ref.seq)
+	if i == len(c.messages) || c.messages[i].GetSeq() != ref.seq {
+		return nil, false
+	}
+
+	return c.messages[i], true
+}
+
+// index returns the position of the first message with a sequence number not lower than seq
+func (c *conversation) index(seq int64) int {
+	return sort.Search(len(c.messages), func(i int) bool {
+		return c.messages[i].GetSeq() >= seq
+	})
+}
+
+func cloneMessages(messages []*pb.Message) []*pb.Message {
+	res := make([]*pb.Message, 0, len(messages))
+	for _, msg := range messages {
+		res = append(res, proto.Clone(msg).(*pb.Message))
+	}
+
+	return res
+}
+
+func cloneGroup(g Group) Group {
+	return Group{
+		Name:   g.Name,
+		Users:  append([]string(nil), g.Users...),
+		Admins: append([]string(nil), g.Admins...),
+	}
+}
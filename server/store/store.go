@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	pb "github.com/vitthalaa/go-grpc-chat/gen/go/chat/v1"
+)
+
+var (
+	// ErrNotFound is returned when a message, attachment or account does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrExists is returned when creating an account whose name is or was in use
+	ErrExists = errors.New("already exists")
+)
+
+// Store is all the storage used by the chat service
+type Store interface {
+	MessageStore
+	ReadMarkerStore
+	AttachmentStore
+	AccountStore
+	GroupStore
+}
+
+// MessageStore persists chat messages per conversation.
+// Conversations are identified by the key returned by ChannelKey.
+// Messages are ordered by their sequence number assigned on append.
+// A zero to time of a range means the range has no upper bound.
+type MessageStore interface {
+	// Append durably stores msg in the conversation key and sets
+	// msg.Seq to the next sequence number of the conversation
+	Append(ctx context.Context, key string, msg *pb.Message) error
+	// Fetch returns messages of the conversation key sent in [from, to)
+	Fetch(ctx context.Context, key string, from, to time.Time) ([]*pb.Message, error)
+	// Last returns at most limit of the newest messages of the conversation key with a
+	// sequence number lower than before, a zero before returns the newest messages
+	Last(ctx context.Context, key string, before int64, limit int) ([]*pb.Message, error)
+	// After returns at most limit of the oldest messages of the conversation key
+	// with a sequence number higher than after
+	After(ctx context.Context, key string, after int64, limit int) ([]*pb.Message, error)
+	// Replies returns at most limit of the oldest replies to the message parentID in the
+	// conversation key with a sequence number higher than after
+	Replies(ctx context.Context, key, parentID string, after int64, limit int) ([]*pb.Message, error)
+	// Get returns the message with the given id or ErrNotFound
+	Get(ctx context.Context, id string) (*pb.Message, error)
+	// Update atomically applies update to the message with the given id and stores the result,
+	// it returns the updated message or ErrNotFound. An error of update is returned as is.
+	// update must not change the id, sequence number or channel of the message
+	Update(ctx context.Context, id string, update func(msg *pb.Message) error) (*pb.Message, error)
+	// Count returns the number of messages of the conversation key with a
+	// sequence number higher than after which were not sent by exclude and are not deleted
+	Count(ctx context.Context, key string, after int64, exclude string) (int64, error)
+	// Delete removes messages of the conversation key sent in [from, to)
+	Delete(ctx context.Context, key string, from, to time.Time) error
+	// Close releases resources held by the store
+	Close() error
+}
+
+// ReadMarkerStore persists the sequence number of the last message a user has read per conversation
+type ReadMarkerStore interface {
+	// SetReadMarker moves the read marker of user in the conversation key forward to seq,
+	// a marker is never moved backwards
+	SetReadMarker(ctx context.Context, user, key string, seq int64) error
+	// ReadMarker returns the read marker of user in the conversation key, 0 when nothing was read
+	ReadMarker(ctx context.Context, user, key string) (int64, error)
+}
+
+// AttachmentStore persists the info of uploaded attachments, their content is kept in a blob store
+type AttachmentStore interface {
+	// PutAttachment stores the info of attachment a
+	PutAttachment(ctx context.Context, a *pb.Attachment) error
+	// Attachment returns the info of the attachment with the given id or ErrNotFound
+	Attachment(ctx context.Context, id string) (*pb.Attachment, error)
+}
+
+// Account is a registered user.
+// Only tokens issued for the current TokenGeneration are accepted, it is increased to revoke all tokens.
+// A deleted account is kept as a tombstone so that its name, which keys the stored direct
+// conversations of the user, is never given to somebody else.
+type Account struct {
+	Username        string    `json:"username"`
+	PasswordHash    []byte    `json:"passwordHash"`
+	CreatedAt       time.Time `json:"createdAt"`
+	TokenGeneration int64     `json:"tokenGeneration"`
+	Deleted         bool      `json:"deleted,omitempty"`
+}
+
+// AccountStore persists the registered users
+type AccountStore interface {
+	// CreateAccount stores a new account or returns ErrExists, also for the name of a deleted account
+	CreateAccount(ctx context.Context, a Account) error
+	// Account returns the account of username or ErrNotFound
+	Account(ctx context.Context, username string) (Account, error)
+	// Accounts returns all accounts which are not deleted
+	Accounts(ctx context.Context) ([]Account, error)
+	// UpdateAccount replaces the stored account of a.Username or returns ErrNotFound
+	UpdateAccount(ctx context.Context, a Account) error
+	// DeleteAccount replaces the account of username with a tombstone or returns ErrNotFound,
+	// Account and UpdateAccount treat deleted accounts as not found
+	DeleteAccount(ctx context.Context, username string) error
+}
+
+// Group is the persisted membership of a group channel
+type Group struct {
+	Name   string   `json:"name"`
+	Users  []string `json:"users"`
+	Admins []string `json:"admins"`
+}
+
+// GroupStore persists groups and their membership so that they outlive a restart
+type GroupStore interface {
+	// PutGroup stores g replacing a stored group of the same name
+	PutGroup(ctx context.Context, g Group) error
+	// DeleteGroup removes the group name, deleting a missing group is not an error
+	DeleteGroup(ctx context.Context, name string) error
+	// Groups returns all stored groups
+	Groups(ctx context.Context) ([]Group, error)
+}
+
+// ChannelKey returns the storage key of the conversation a message sent by sender to channel belongs to.
+// Direct messages between two users share one key regardless of who sent them.
+func ChannelKey(channel *pb.Channel, sender string) string {
+	if channel.GetType() == pb.ChannelType_GROUP {
+		return "group/" + channel.GetName()
+	}
+
+	users := []string{sender, channel.GetName()}
+	sort.Strings(users)
+
+	return "direct/" + users[0] + "/" + users[1]
+}
+
+// inRange reports whether msg was sent in [from, to)
+func inRange(msg *pb.Message, from, to time.Time) bool {
+	t := msg.GetTime().AsTime()
+
+	return !t.Before(from) && (to.IsZero() || t.Before(to))
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// testStores returns a memory store and a bolt store in a temporary directory
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+
+	t.Cleanup(func() { bolt.Close() })
+
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"bolt":   bolt,
+	}
+}
+
+func TestDeletedAccountNameIsNotReused(t *testing.T) {
+	ctx := context.Background()
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			err := st.CreateAccount(ctx, Account{Username: "bob", PasswordHash: []byte("hash")})
+			if err != nil {
+				t.Fatalf("CreateAccount: %v", err)
+			}
+
+			err = st.DeleteAccount(ctx, "bob")
+			if err != nil {
+				t.Fatalf("DeleteAccount: %v", err)
+			}
+
+			_, err = st.Account(ctx, "bob")
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("Account of deleted bob: %v, want ErrNotFound", err)
+			}
+
+			err = st.UpdateAccount(ctx, Account{Username: "bob"})
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("UpdateAccount of deleted bob: %v, want ErrNotFound", err)
+			}
+
+			err = st.DeleteAccount(ctx, "bob")
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("DeleteAccount of deleted bob: %v, want ErrNotFound", err)
+			}
+
+			err = st.CreateAccount(ctx, Account{Username: "bob"})
+			if !errors.Is(err, ErrExists) {
+				t.Errorf("CreateAccount of deleted bob: %v, want ErrExists", err)
+			}
+		})
+	}
+}